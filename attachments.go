@@ -0,0 +1,227 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// previewAttachments maps the attachment lines of the preview to their
+// attachments. File names can look like issue keys, the line can't be
+// parsed back.
+var previewAttachments = map[string]*jira.Attachment{}
+
+func formatSize(size int) string {
+    switch {
+    case size >= 1024*1024:
+        return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+    case size >= 1024:
+        return fmt.Sprintf("%.1f KB", float64(size)/1024)
+    }
+    return fmt.Sprintf("%d B", size)
+}
+
+func printAttachments(v io.Writer, issue jira.Issue) {
+
+    previewAttachments = map[string]*jira.Attachment{}
+    if len(issue.Fields.Attachments) == 0 {
+        return
+    }
+
+    fmt.Fprint(v, "\nAttachments:\n")
+    for _, attachment := range issue.Fields.Attachments {
+        author := ""
+        if attachment.Author != nil {
+            author = ", " + attachment.Author.DisplayName
+        }
+        created := attachment.Created
+        if len(created) >= 10 {
+            created = created[:10]
+        }
+        line := "  " + attachment.Filename + " (" + formatSize(attachment.Size) + author + ", " + created + ")"
+        previewAttachments[line] = attachment
+        fmt.Fprintln(v, line)
+    }
+}
+
+// attachmentUnderCursor finds the attachment on the highlighted line
+func attachmentUnderCursor(v *gocui.View) *jira.Attachment {
+    _, cy := v.Cursor()
+    line, err := v.Line(cy)
+    if err != nil {
+        return nil
+    }
+    return previewAttachments[line]
+}
+
+// downloadDir is where attachments are saved
+func downloadDir() string {
+    if conf, err := readConfig(); err == nil && conf.downloadDir != "" {
+        return expandHome(conf.downloadDir)
+    }
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return os.TempDir()
+    }
+    return filepath.Join(home, "Downloads")
+}
+
+func expandHome(path string) string {
+    if path == "~" || strings.HasPrefix(path, "~/") {
+        if home, err := os.UserHomeDir(); err == nil {
+            return filepath.Join(home, strings.TrimPrefix(path, "~"))
+        }
+    }
+    return path
+}
+
+// downloadAttachment saves the attachment into the directory, and tells
+// where it went.
+func downloadAttachment(attachment *jira.Attachment, dir string) (string, error) {
+
+    if err := ensureAuthenticated(); err != nil {
+        return "", err
+    }
+    res, err := jiraClient.Issue.DownloadAttachment(attachment.ID)
+    if err != nil {
+        return "", newJiraError(res, err)
+    }
+    defer res.Body.Close()
+
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return "", err
+    }
+    // The name comes from JIRA, keep it inside the directory
+    path := filepath.Join(dir, filepath.Base(attachment.Filename))
+    file, err := os.Create(path)
+    if err != nil {
+        return "", err
+    }
+    defer file.Close()
+
+    if _, err := io.Copy(file, res.Body); err != nil {
+        return "", err
+    }
+    return path, nil
+}
+
+func saveAttachmentHandler(g *gocui.Gui, v *gocui.View) error {
+
+    attachment := attachmentUnderCursor(v)
+    if attachment == nil {
+        updateStatusBar(g, "Choose an attachment to save it")
+        return nil
+    }
+
+    path, err := downloadAttachment(attachment, downloadDir())
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    updateStatusBar(g, "Saved to "+path)
+    return nil
+}
+
+// openAttachment downloads the attachment to a temporary directory and
+// opens it like we open issues, with the browser command.
+func openAttachment(g *gocui.Gui, attachment *jira.Attachment) error {
+
+    conf, err := readConfig()
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    dir, err := ioutil.TempDir("", "jb-")
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    path, err := downloadAttachment(attachment, dir)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    if err := exec.Command(conf.browserCommand, path).Start(); err != nil {
+        showError(g, &jbError{kind: errConfig, err: err})
+        return nil
+    }
+    updateStatusBar(g, "Opened "+attachment.Filename)
+    return nil
+}
+
+func uploadAttachmentHandler(g *gocui.Gui, v *gocui.View) error {
+    issueKey := previewed.Key
+    err := openPrompt(g, "Attach file to "+issueKey, "", func(g *gocui.Gui, path string) error {
+        if path == "" {
+            return nil
+        }
+        return uploadAttachment(g, issueKey, expandHome(path))
+    })
+    promptCompleter = completePath
+    updateStatusBar(g, "Done: Enter  |  Complete: Tab  |  Cancel: Esc")
+    return err
+}
+
+func uploadAttachment(g *gocui.Gui, issueKey string, path string) error {
+
+    file, err := os.Open(path)
+    if err != nil {
+        showError(g, &jbError{kind: errValidation, err: err})
+        return nil
+    }
+    defer file.Close()
+
+    if err := ensureAuthenticated(); err != nil {
+        showError(g, err)
+        return nil
+    }
+    if _, res, err := jiraClient.Issue.PostAttachment(issueKey, file, filepath.Base(path)); err != nil {
+        showError(g, newJiraError(res, err))
+        return nil
+    }
+
+    if err := reloadPreview(g); err != nil {
+        return err
+    }
+    updateStatusBar(g, filepath.Base(path)+" is attached to "+issueKey)
+    return nil
+}
+
+// completePath completes the path as far as it is not ambiguous, and
+// lists the candidates otherwise.
+func completePath(text string) (string, []string) {
+
+    matches, err := filepath.Glob(expandHome(text) + "*")
+    if err != nil || len(matches) == 0 {
+        return text, nil
+    }
+    sort.Strings(matches)
+
+    common := matches[0]
+    for _, match := range matches[1:] {
+        for !strings.HasPrefix(match, common) {
+            common = common[:len(common)-1]
+        }
+    }
+    if len(matches) == 1 {
+        if info, err := os.Stat(common); err == nil && info.IsDir() {
+            common = common + string(os.PathSeparator)
+        }
+        return common, nil
+    }
+
+    names := []string{}
+    for _, match := range matches {
+        names = append(names, filepath.Base(match))
+    }
+    return common, names
+}
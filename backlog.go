@@ -0,0 +1,227 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+var (
+    backlogIssues   = []jira.Issue{}
+    backlogShown    = []jira.Issue{}
+    backlogMarked   = map[string]bool{}
+    backlogSearch   = ""
+    backlogInfoText = "Actions: Spacebar  |  Preview: p  |  Search: /  |  Mark: x  |  Move to column: m  |  Board: B or Esc"
+)
+
+var orderByPattern = regexp.MustCompile(`(?i)\s+order\s+by\s+.*$`)
+
+// backlogQuery is the query of the board, without the issues which are
+// on the board. In scrum mode those are the ones in an open sprint,
+// otherwise the ones in a column.
+func backlogQuery(conf configItem) string {
+
+    query := "(" + orderByPattern.ReplaceAllString(conf.query, "") + ")"
+    if conf.boardID != 0 {
+        query = query + " AND (sprint is EMPTY OR sprint not in openSprints() OR sprint in futureSprints())"
+    } else if len(configColumns) > 0 {
+        quoted := []string{}
+        for _, column := range configColumns {
+            quoted = append(quoted, `"`+column+`"`)
+        }
+        query = query + " AND status not in (" + strings.Join(quoted, ", ") + ")"
+    }
+    return query + " ORDER BY Rank ASC"
+}
+
+func fetchBacklog(conf configItem) ([]jira.Issue, error) {
+    return searchAll(backlogQuery(conf))
+}
+
+func toggleBacklog(g *gocui.Gui, v *gocui.View) error {
+    if _, err := g.View("backlog"); err == nil {
+        return closeBacklog(g, v)
+    }
+    return loadBacklog(g)
+}
+
+// loadBacklog fetches the backlog and shows it over the board
+func loadBacklog(g *gocui.Gui) error {
+
+    conf, err := readConfig()
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    updateStatusBar(g, "Loading backlog...")
+
+    issues, err := fetchBacklog(conf)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    backlogIssues = issues
+
+    maxX, maxY := g.Size()
+    v, err := g.SetView("backlog", 0, 0, maxX-1, maxY-2)
+    if err != nil && err != gocui.ErrUnknownView {
+        return err
+    }
+    v.Title = "Backlog"
+    v.Editable = false
+    v.Highlight = true
+    v.Wrap = false
+    drawBacklog(g)
+
+    setCurrentViewOnTop(g, "backlog")
+    updateStatusBar(g, backlogInfoText)
+    return nil
+}
+
+func backlogLine(issue jira.Issue) string {
+    mark := "  "
+    if backlogMarked[issue.Key] {
+        mark = "* "
+    }
+    status := ""
+    if issue.Fields.Status != nil {
+        status = issue.Fields.Status.Name
+    }
+    priority := ""
+    if issue.Fields.Priority != nil {
+        priority = issue.Fields.Priority.Name
+    }
+    return fmt.Sprintf("%s%-10s %-14s %s %s", mark, issue.Key, status, colorPriority(priority, fmt.Sprintf("%-9s", priority)), issue.Fields.Summary)
+}
+
+// drawBacklog writes the issues matching the search, in rank order
+func drawBacklog(g *gocui.Gui) {
+
+    v, err := g.View("backlog")
+    if err != nil {
+        return
+    }
+
+    search := strings.ToLower(backlogSearch)
+    backlogShown = []jira.Issue{}
+    for _, issue := range backlogIssues {
+        if search != "" && !strings.Contains(strings.ToLower(issue.Key+" "+issue.Fields.Summary), search) {
+            continue
+        }
+        backlogShown = append(backlogShown, issue)
+    }
+
+    v.Title = fmt.Sprintf("Backlog, %d issues", len(backlogShown))
+    if backlogSearch != "" {
+        v.Title = v.Title + " matching \"" + backlogSearch + "\""
+    }
+    if len(backlogMarked) > 0 {
+        v.Title = v.Title + fmt.Sprintf(", %d marked", len(backlogMarked))
+    }
+
+    _, cy := v.Cursor()
+    _, oy := v.Origin()
+    v.Clear()
+    for _, issue := range backlogShown {
+        fmt.Fprintln(v, backlogLine(issue))
+    }
+    if cy+oy >= len(backlogShown) {
+        v.SetOrigin(0, 0)
+        v.SetCursor(0, 0)
+    }
+}
+
+// backlogIssue is the issue under the cursor
+func backlogIssue(v *gocui.View) (jira.Issue, bool) {
+    _, cy := v.Cursor()
+    _, oy := v.Origin()
+    if cy+oy >= len(backlogShown) {
+        return jira.Issue{}, false
+    }
+    return backlogShown[cy+oy], true
+}
+
+func closeBacklog(g *gocui.Gui, v *gocui.View) error {
+    backlogMarked = map[string]bool{}
+    backlogSearch = ""
+    g.DeleteView("backlog")
+    activateFirstIssue(g)
+    updateStatusBar(g, infoText)
+    return nil
+}
+
+func backlogMenu(g *gocui.Gui, v *gocui.View) error {
+    issue, ok := backlogIssue(v)
+    if !ok {
+        return nil
+    }
+    maxX, maxY := g.Size()
+    _, cy := v.Cursor()
+    menuCoord := [4]int{maxX/2 - 16, cy + 1, maxX/2 + 16, cy + 15}
+    if menuCoord[3] > maxY-2 {
+        menuCoord = [4]int{maxX/2 - 16, cy - 14, maxX/2 + 16, cy}
+    }
+    return openIssueMenu(g, issue, menuCoord)
+}
+
+func backlogPreview(g *gocui.Gui, v *gocui.View) error {
+    issue, ok := backlogIssue(v)
+    if !ok {
+        return nil
+    }
+    previewHistory = []jira.Issue{}
+    return showPreview(g, issue)
+}
+
+func backlogMark(g *gocui.Gui, v *gocui.View) error {
+    issue, ok := backlogIssue(v)
+    if !ok {
+        return nil
+    }
+    if backlogMarked[issue.Key] {
+        delete(backlogMarked, issue.Key)
+    } else {
+        backlogMarked[issue.Key] = true
+    }
+    drawBacklog(g)
+    return cursorDown(g, v)
+}
+
+func backlogSearchHandler(g *gocui.Gui, v *gocui.View) error {
+    return openPrompt(g, "Search the backlog, empty to show all", backlogSearch, func(g *gocui.Gui, text string) error {
+        backlogSearch = text
+        drawBacklog(g)
+        return nil
+    })
+}
+
+// backlogMoveHandler moves the marked issues, or the one under the
+// cursor, to a column of the board.
+func backlogMoveHandler(g *gocui.Gui, v *gocui.View) error {
+
+    issues := []jira.Issue{}
+    for _, issue := range backlogIssues {
+        if backlogMarked[issue.Key] {
+            issues = append(issues, issue)
+        }
+    }
+    if len(issues) == 0 {
+        issue, ok := backlogIssue(v)
+        if !ok {
+            return nil
+        }
+        issues = append(issues, issue)
+    }
+
+    title := fmt.Sprintf("Move %d issues to", len(issues))
+    return openPicker(g, title, configColumns, func(g *gocui.Gui, column string) error {
+        backlogMarked = map[string]bool{}
+        runBulk(g, "Moving to "+column, issues, func(client *jira.Client, issue jira.Issue) error {
+            return transitionTo(client, issue, column)
+        })
+        return nil
+    })
+}
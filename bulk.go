@@ -0,0 +1,245 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// markedCards are the issues the next action of the menu runs on
+var markedCards = map[string]bool{}
+
+// markMarker is shown in the status bar while cards are marked
+func markMarker() string {
+    marked := len(markedIssues())
+    if marked == 0 {
+        return ""
+    }
+    return "[" + strconv.Itoa(marked) + " marked] "
+}
+
+// markedIssues lists the marked issues which are still on the board
+func markedIssues() []jira.Issue {
+    issues := []jira.Issue{}
+    for _, issue := range boardIssues() {
+        if markedCards[issue.Key] {
+            issues = append(issues, issue)
+        }
+    }
+    return issues
+}
+
+// setMarks marks the issues, or unmarks them if all are marked already
+func setMarks(g *gocui.Gui, issues []jira.Issue) {
+    allMarked := true
+    for _, issue := range issues {
+        allMarked = allMarked && markedCards[issue.Key]
+    }
+    for _, issue := range issues {
+        if allMarked {
+            delete(markedCards, issue.Key)
+        } else {
+            markedCards[issue.Key] = true
+        }
+        redrawCard(g, issue)
+    }
+    updateStatusBar(g, infoText)
+}
+
+func clearMarks(g *gocui.Gui) {
+    issues := markedIssues()
+    markedCards = map[string]bool{}
+    for _, issue := range issues {
+        redrawCard(g, issue)
+    }
+}
+
+func markCardHandler(g *gocui.Gui, v *gocui.View) error {
+    currentColumn, err := getColumn(active.columnname)
+    if err != nil || len(currentColumn.members) == 0 {
+        return nil
+    }
+    setMarks(g, []jira.Issue{currentColumn.members[active.indexno].issue})
+    return nil
+}
+
+func markColumnHandler(g *gocui.Gui, v *gocui.View) error {
+    currentColumn, err := getColumn(active.columnname)
+    if err != nil {
+        return nil
+    }
+    issues := []jira.Issue{}
+    for _, box := range currentColumn.members {
+        issues = append(issues, box.issue)
+    }
+    setMarks(g, issues)
+    return nil
+}
+
+// markAllHandler marks every card on the board, the ones hidden by the
+// epic filter stay as they are.
+func markAllHandler(g *gocui.Gui, v *gocui.View) error {
+    issues := []jira.Issue{}
+    for i := range kanbanMatrix {
+        for _, box := range kanbanMatrix[i].members {
+            issues = append(issues, box.issue)
+        }
+    }
+    setMarks(g, issues)
+    return nil
+}
+
+// openBulkMenu offers the actions which can run on all marked cards
+func openBulkMenu(g *gocui.Gui) error {
+
+    issues := markedIssues()
+    options := []string{}
+    for _, column := range configColumns {
+        options = append(options, "Send to -> "+column)
+    }
+    options = append(options, "Assign to", "Add label", "Remove label", "Set components", "Comment", "Clear marks")
+
+    title := fmt.Sprintf("%d marked issues", len(issues))
+    return openPicker(g, title, options, func(g *gocui.Gui, choice string) error {
+
+        run := func(label string, action func(client *jira.Client, issue jira.Issue) error) error {
+            clearMarks(g)
+            runBulk(g, label, issues, action)
+            return nil
+        }
+        ask := func(question string, action func(text string) error) error {
+            return openPrompt(g, question, "", func(g *gocui.Gui, text string) error {
+                if text == "" {
+                    return nil
+                }
+                return action(text)
+            })
+        }
+
+        switch choice {
+        case "Assign to":
+            return ask("Assign to (username)", func(name string) error {
+                return run("Assigning to "+name, func(client *jira.Client, issue jira.Issue) error {
+                    res, err := client.Issue.UpdateAssignee(issue.ID, &jira.User{Name: name})
+                    return newJiraError(res, err)
+                })
+            })
+        case "Add label", "Remove label":
+            operation := "add"
+            if choice == "Remove label" {
+                operation = "remove"
+            }
+            return ask(choice, func(label string) error {
+                return run(choice+" "+label, func(client *jira.Client, issue jira.Issue) error {
+                    res, err := client.Issue.UpdateIssue(issue.Key, map[string]interface{}{
+                        "update": map[string]interface{}{
+                            "labels": []map[string]string{{operation: label}},
+                        },
+                    })
+                    return newJiraError(res, err)
+                })
+            })
+        case "Set components":
+            return ask("Components (comma separated)", func(text string) error {
+                components := []map[string]string{}
+                for _, name := range strings.Split(text, ",") {
+                    if name = strings.TrimSpace(name); name != "" {
+                        components = append(components, map[string]string{"name": name})
+                    }
+                }
+                return run("Setting components", func(client *jira.Client, issue jira.Issue) error {
+                    res, err := client.Issue.UpdateIssue(issue.Key, map[string]interface{}{
+                        "fields": map[string]interface{}{"components": components},
+                    })
+                    return newJiraError(res, err)
+                })
+            })
+        case "Comment":
+            return ask("Comment on all of them", func(body string) error {
+                return run("Commenting", func(client *jira.Client, issue jira.Issue) error {
+                    _, res, err := client.Issue.AddComment(issue.ID, &jira.Comment{Body: body})
+                    return newJiraError(res, err)
+                })
+            })
+        case "Clear marks":
+            clearMarks(g)
+            updateStatusBar(g, infoText)
+            return nil
+        }
+
+        column := strings.TrimPrefix(choice, "Send to -> ")
+        return run("Moving to "+column, func(client *jira.Client, issue jira.Issue) error {
+            return transitionTo(client, issue, column)
+        })
+    })
+}
+
+// runBulk runs the action on every issue in the background, showing the
+// progress in the status bar. The issues which failed are listed at the
+// end, and the board is loaded again. The action gets the client as it
+// was when the run started.
+func runBulk(g *gocui.Gui, label string, issues []jira.Issue, action func(client *jira.Client, issue jira.Issue) error) {
+
+    // Logging in replaces jiraClient, it can't happen in the goroutine
+    if err := ensureAuthenticated(); err != nil {
+        showError(g, err)
+        return
+    }
+    client := jiraClient
+    go func() {
+        failures := []string{}
+        for i, issue := range issues {
+            done := i
+            g.Update(func(g *gocui.Gui) error {
+                updateStatusBar(g, label+" "+progressBar(done, len(issues), 20)+" "+strconv.Itoa(done)+"/"+strconv.Itoa(len(issues)))
+                return nil
+            })
+
+            if err := action(client, issue); err != nil {
+                failures = append(failures, issue.Key+": "+err.Error())
+            }
+        }
+
+        g.Update(func(g *gocui.Gui) error {
+            if err := refreshBoard(g, nil); err != nil {
+                return err
+            }
+            if len(failures) > 0 {
+                showError(g, errors.New(
+                    label+" failed for "+strconv.Itoa(len(failures))+" of "+strconv.Itoa(len(issues))+" issues:\n"+strings.Join(failures, "\n"),
+                ))
+                return nil
+            }
+            updateStatusBar(g, label+": done for "+strconv.Itoa(len(issues))+" issues")
+            return nil
+        })
+    }()
+}
+
+// transitionTo moves the issue to the column. Transitions with required
+// fields need their screen, those have to be done one by one.
+func transitionTo(client *jira.Client, issue jira.Issue, column string) error {
+
+    // Not getTransitions, the cache belongs to the UI
+    transitions, res, err := client.Issue.GetTransitions(issue.ID)
+    if err != nil {
+        return newJiraError(res, err)
+    }
+    for _, transition := range transitions {
+        if transition.To.Name != column {
+            continue
+        }
+        for _, field := range transition.Fields {
+            if field.Required {
+                return errors.New("the transition to " + column + " needs a screen to be filled")
+            }
+        }
+        res, err := client.Issue.DoTransition(issue.ID, transition.ID)
+        return newJiraError(res, err)
+    }
+    return errors.New("can't be moved to " + column)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strconv"
+    "sync"
+    "time"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// boardCache is the last board we could load, so we have something to
+// show while JIRA is far away.
+type boardCache struct {
+    SavedAt       time.Time                    `json:"savedAt"`
+    Query         string                       `json:"query"`
+    Issues        []jira.Issue                 `json:"issues"`
+    Transitions   map[string][]jira.Transition `json:"transitions"`
+    Comments      map[string][]*jira.Comment   `json:"comments"`
+    Epics         map[string]string            `json:"epics"`
+    EpicLinkField string                       `json:"epicLinkField"`
+    FlagField     string                       `json:"flagField"`
+    Sprint        jira.Sprint                  `json:"sprint"`
+}
+
+// pendingOp is a change made while offline, waiting to be sent
+type pendingOp struct {
+    Kind     string          `json:"kind"`
+    IssueID  string          `json:"issueId"`
+    IssueKey string          `json:"issueKey"`
+    Body     string          `json:"body,omitempty"`
+    Payload  json.RawMessage `json:"payload,omitempty"`
+    QueuedAt time.Time       `json:"queuedAt"`
+}
+
+var (
+    cache = &boardCache{
+        Transitions: map[string][]jira.Transition{},
+        Comments:    map[string][]*jira.Comment{},
+        Epics:       map[string]string{},
+    }
+    pendingOps = []pendingOp{}
+    pendingMu  sync.Mutex
+    syncMu     sync.Mutex
+    staleSince time.Time
+)
+
+// jbDir is where we keep our own state, next to the config
+func jbDir() string {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        home = os.Getenv("HOME")
+    }
+    return filepath.Join(home, ".jb")
+}
+
+func cacheDir() string {
+    return filepath.Join(jbDir(), "cache")
+}
+
+// isOffline tells if the error means we couldn't reach JIRA at all
+func isOffline(err error) bool {
+    return errorKindOf(err) == errNetwork
+}
+
+func readCacheFile(name string, v interface{}) error {
+    return readJSONFile(cacheDir(), name, v)
+}
+
+func writeCacheFile(name string, v interface{}) {
+    writeJSONFile(cacheDir(), name, v)
+}
+
+func readJSONFile(dir string, name string, v interface{}) error {
+    content, err := ioutil.ReadFile(filepath.Join(dir, name))
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(content, v)
+}
+
+// writeJSONFile writes to a temporary file first, so we never leave a
+// half written file behind.
+func writeJSONFile(dir string, name string, v interface{}) {
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        log.Warn("Couldn't create " + dir + ": " + err.Error())
+        return
+    }
+    content, err := json.Marshal(v)
+    if err != nil {
+        log.Warn("Couldn't encode " + name + ": " + err.Error())
+        return
+    }
+    path := filepath.Join(dir, name)
+    if err := ioutil.WriteFile(path+".tmp", content, 0600); err != nil {
+        log.Warn("Couldn't write " + name + ": " + err.Error())
+        return
+    }
+    if err := os.Rename(path+".tmp", path); err != nil {
+        log.Warn("Couldn't write " + name + ": " + err.Error())
+    }
+}
+
+func loadCache() {
+    loaded := boardCache{}
+    if err := readCacheFile("board.json", &loaded); err != nil {
+        log.Info("No usable board cache: " + err.Error())
+    } else {
+        if loaded.Transitions == nil {
+            loaded.Transitions = map[string][]jira.Transition{}
+        }
+        if loaded.Comments == nil {
+            loaded.Comments = map[string][]*jira.Comment{}
+        }
+        if loaded.Epics == nil {
+            loaded.Epics = map[string]string{}
+        }
+        cache = &loaded
+    }
+
+    pendingMu.Lock()
+    defer pendingMu.Unlock()
+    if err := readCacheFile("pending.json", &pendingOps); err != nil {
+        pendingOps = []pendingOp{}
+    }
+}
+
+// saveBoardCache stores the issues as they are on the board now
+func saveBoardCache() {
+    cache.Issues = boardIssues()
+    writeCacheFile("board.json", cache)
+}
+
+// renderCachedBoard puts the cached issues on the board, if the cache
+// was made for the query we have.
+func renderCachedBoard(g *gocui.Gui, conf configItem) error {
+    if cache.Query != boardQuery(conf) || len(cache.Issues) == 0 {
+        return nil
+    }
+
+    if err := placeIssues(g, cache.Issues); err != nil {
+        return err
+    }
+    staleSince = cache.SavedAt
+
+    updateStatusBar(g, "Loading issues...")
+    return nil
+}
+
+func cacheTransitions(issueID string, transitions []jira.Transition) {
+    cache.Transitions[issueID] = transitions
+    writeCacheFile("board.json", cache)
+}
+
+func cacheComments(issueKey string, comments []*jira.Comment) {
+    cache.Comments[issueKey] = comments
+    writeCacheFile("board.json", cache)
+}
+
+// offlineMarker is shown in front of the status bar messages
+func offlineMarker() string {
+    marker := ""
+    if !staleSince.IsZero() {
+        marker = "[stale since " + staleSince.Format("15:04") + "] "
+    }
+    pendingMu.Lock()
+    defer pendingMu.Unlock()
+    if len(pendingOps) > 0 {
+        marker = marker + "[" + strconv.Itoa(len(pendingOps)) + " pending] "
+    }
+    return marker
+}
+
+func queueOp(op pendingOp) {
+    op.QueuedAt = time.Now()
+    pendingMu.Lock()
+    defer pendingMu.Unlock()
+    pendingOps = append(pendingOps, op)
+    writeCacheFile("pending.json", pendingOps)
+}
+
+// queueTransition remembers the transition for later and moves the card
+// on the board, as if JIRA already accepted it.
+func queueTransition(g *gocui.Gui, issueID string, issueKey string, toStatus string, payload interface{}) error {
+    content, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+    queueOp(pendingOp{Kind: "transition", IssueID: issueID, IssueKey: issueKey, Payload: content})
+
+    for i := range kanbanMatrix {
+        if indexOfView(issueKey, kanbanMatrix[i].members) >= 0 && kanbanMatrix[i].view.Title != toStatus {
+            relocateCard(g, issueKey, kanbanMatrix[i].view.Title, toStatus, -1)
+            break
+        }
+    }
+    saveBoardCache()
+
+    updateStatusBar(g, "Offline, "+issueKey+" will be moved to "+toStatus+" when JIRA is reachable")
+    return nil
+}
+
+// pendingComments returns the comments waiting to be sent for the issue
+func pendingComments(issueKey string) []string {
+    pendingMu.Lock()
+    defer pendingMu.Unlock()
+    comments := []string{}
+    for _, op := range pendingOps {
+        if op.Kind == "comment" && op.IssueKey == issueKey {
+            comments = append(comments, op.Body)
+        }
+    }
+    return comments
+}
+
+func hasPendingOps() bool {
+    pendingMu.Lock()
+    defer pendingMu.Unlock()
+    return len(pendingOps) > 0
+}
+
+// syncPendingOps sends the queued changes in order with the given,
+// logged in client. It stops at the first one which can't reach JIRA,
+// and drops the ones JIRA refuses. Returns how many of them were sent.
+func syncPendingOps(g *gocui.Gui, client *jira.Client) int {
+
+    // Someone else is already at it
+    if !syncMu.TryLock() {
+        return 0
+    }
+    defer syncMu.Unlock()
+
+    pendingMu.Lock()
+    ops := append([]pendingOp{}, pendingOps...)
+    pendingMu.Unlock()
+
+    if len(ops) == 0 {
+        return 0
+    }
+
+    sent := 0
+    done := 0
+    for _, op := range ops {
+        var res *jira.Response
+        var err error
+        switch op.Kind {
+        case "comment":
+            _, res, err = client.Issue.AddComment(op.IssueID, &jira.Comment{Body: op.Body})
+        case "transition":
+            res, err = client.Issue.DoTransitionWithPayload(op.IssueID, op.Payload)
+        }
+        err = newJiraError(res, err)
+
+        if err != nil && isOffline(err) {
+            break
+        }
+        if err != nil {
+            dropped := &jbError{
+                kind: errorKindOf(err),
+                err:  errors.New("Dropped the offline " + op.Kind + " of " + op.IssueKey + ". " + err.Error()),
+            }
+            g.Update(func(g *gocui.Gui) error {
+                showError(g, dropped)
+                return nil
+            })
+        } else {
+            sent++
+        }
+        done++
+    }
+
+    // New changes might have been queued meanwhile, they stay
+    pendingMu.Lock()
+    pendingOps = pendingOps[done:]
+    writeCacheFile("pending.json", pendingOps)
+    pendingMu.Unlock()
+
+    return sent
+}
+
+// backgroundState is what the sync needs from the UI goroutine, which
+// owns jiraClient and the globals readConfig sets.
+type backgroundState struct {
+    client        *jira.Client
+    authenticated bool
+    conf          configItem
+    err           error
+}
+
+// syncInBackground keeps trying to send the queued changes, and
+// refreshes the board when some of them went through. Logging in is
+// done with its own client, which the UI takes over if it has none.
+func syncInBackground(g *gocui.Gui, interval time.Duration) {
+    for range time.Tick(interval) {
+        if !hasPendingOps() {
+            continue
+        }
+
+        states := make(chan backgroundState, 1)
+        g.Update(func(g *gocui.Gui) error {
+            conf, err := readConfig()
+            states <- backgroundState{jiraClient, jiraClient.Authentication.Authenticated(), conf, err}
+            return nil
+        })
+        state := <-states
+        if state.err != nil {
+            continue
+        }
+
+        client := state.client
+        if !state.authenticated {
+            loggedIn, err := getJiraAuth(state.conf)
+            if err != nil {
+                continue
+            }
+            client = loggedIn
+            g.Update(func(g *gocui.Gui) error {
+                if !jiraClient.Authentication.Authenticated() {
+                    jiraClient = loggedIn
+                }
+                return nil
+            })
+        }
+
+        sent := syncPendingOps(g, client)
+        if sent == 0 {
+            continue
+        }
+        g.Update(func(g *gocui.Gui) error {
+            // Don't pull the board away under an open popup
+            if !boardHasFocus(g) {
+                updateStatusBar(g, "Sent "+strconv.Itoa(sent)+" offline changes, reload with F5")
+                return nil
+            }
+            return refreshBoard(g, nil)
+        })
+    }
+}
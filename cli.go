@@ -0,0 +1,283 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+)
+
+// command is something jb does without the board, for scripts
+type command struct {
+    usage string
+    run   func(args []string) error
+}
+
+var commands = map[string]command{
+    "list":    {"list [--board ID] [--jql QUERY] [--output FORMAT [--fields FIELD,...]]", listCommand},
+    "show":    {"show KEY", showCommand},
+    "move":    {"move KEY STATUS", moveCommand},
+    "assign":  {"assign KEY USERNAME", assignCommand},
+    "comment": {"comment KEY [-m MESSAGE]   (the message is read from stdin without -m)", commentCommand},
+    "open":    {"open KEY", openCommand},
+    "current": {"current [--summary]   (the issue of the checked out git branch)", currentCommand},
+}
+
+var commandOrder = []string{"list", "show", "move", "assign", "comment", "open", "current"}
+
+// exitCodes tell scripts what went wrong
+var exitCodes = map[errorKind]int{
+    errUnknown:    1,
+    errUsage:      2,
+    errConfig:     3,
+    errAuth:       4,
+    errPermission: 5,
+    errValidation: 6,
+    errNetwork:    7,
+}
+
+func printCommands() {
+    fmt.Println("Commands, the board is shown without one:")
+    fmt.Println()
+    for _, name := range commandOrder {
+        fmt.Println("  jb " + commands[name].usage)
+    }
+}
+
+// runCommand runs the command and tells the exit code
+func runCommand(args []string) int {
+
+    cmd, ok := commands[args[0]]
+    if !ok {
+        fmt.Fprintln(os.Stderr, "Unknown command: "+args[0])
+        printCommands()
+        return exitCodes[errUsage]
+    }
+
+    // current runs for every shell prompt, it needs neither of them
+    if args[0] != "current" {
+        loadCache()
+        loadTimers()
+    }
+    if err := cmd.run(args[1:]); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        if errorKindOf(err) == errUsage {
+            fmt.Fprintln(os.Stderr, "Usage: jb "+cmd.usage)
+        }
+        return exitCodes[errorKindOf(err)]
+    }
+    return 0
+}
+
+func usageError(message string) error {
+    return &jbError{kind: errUsage, err: errors.New(message)}
+}
+
+// parseCommand parses the flags of the command, which can come before
+// or after the other arguments, and checks the number of the latter.
+func parseCommand(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+
+    flags.SetOutput(ioutil.Discard)
+    positional := []string{}
+    for {
+        if err := flags.Parse(args); err != nil {
+            return nil, usageError(err.Error())
+        }
+        args = flags.Args()
+        if len(args) == 0 {
+            break
+        }
+        positional = append(positional, args[0])
+        args = args[1:]
+    }
+    if len(positional) != count {
+        return nil, usageError("wrong number of arguments for " + flags.Name())
+    }
+    return positional, nil
+}
+
+// searchAll fetches every issue of the query, page by page
+func searchAll(query string) ([]jira.Issue, error) {
+
+    if err := ensureAuthenticated(); err != nil {
+        return nil, err
+    }
+
+    // Not SearchPages, it drops the response and with it the status code
+    issues := []jira.Issue{}
+    options := &jira.SearchOptions{MaxResults: 100}
+    for {
+        page, res, err := jiraClient.Issue.Search(query, options)
+        if err != nil {
+            return nil, newJiraError(res, err)
+        }
+        issues = append(issues, page...)
+        if len(page) == 0 || res == nil || res.StartAt+len(page) >= res.Total {
+            return issues, nil
+        }
+        options.StartAt += len(page)
+    }
+}
+
+// listCommand prints the issues of the board query, in the active
+// sprint in scrum mode. The query can be replaced with --jql. With
+// --output the issues are grouped into the columns of the board.
+func listCommand(args []string) error {
+
+    flags := flag.NewFlagSet("list", flag.ContinueOnError)
+    board := flags.Int("board", 0, "")
+    jql := flags.String("jql", "", "")
+    output := flags.String("output", "", "")
+    fieldList := flags.String("fields", "", "")
+    if _, err := parseCommand(flags, args, 0); err != nil {
+        return err
+    }
+    fields, err := parseFields(*fieldList)
+    if err != nil {
+        return err
+    }
+    if *output != "" && indexOf(*output, outputFormats) < 0 {
+        return usageError("unknown output format \"" + *output + "\", it can be " + strings.Join(outputFormats, ", "))
+    }
+
+    conf, err := readConfig()
+    if err != nil {
+        return err
+    }
+    if *board != 0 {
+        conf.boardID = *board
+    }
+    if *jql != "" {
+        conf.query = *jql
+    }
+    if conf.boardID != 0 {
+        // Not the sprint the board showed last
+        cache.Sprint = jira.Sprint{}
+        if err := loadSprints(conf); err != nil {
+            return err
+        }
+    }
+
+    issues, err := searchAll(boardQuery(conf))
+    if err != nil {
+        return err
+    }
+    if *output != "" {
+        resolveCustomFields(conf)
+        resolveEpics(issues)
+        return writeBoard(os.Stdout, *output, groupIssues(issues), fields)
+    }
+    for _, issue := range issues {
+        status := ""
+        if issue.Fields.Status != nil {
+            status = issue.Fields.Status.Name
+        }
+        assignee := ""
+        if issue.Fields.Assignee != nil {
+            assignee = issue.Fields.Assignee.Name
+        }
+        fmt.Println(issue.Key + "\t" + status + "\t" + assignee + "\t" + issue.Fields.Summary)
+    }
+    return nil
+}
+
+func showCommand(args []string) error {
+
+    positional, err := parseCommand(flag.NewFlagSet("show", flag.ContinueOnError), args, 1)
+    if err != nil {
+        return err
+    }
+    issue, err := fetchIssue(positional[0])
+    if err != nil {
+        return err
+    }
+    var client *jira.Client
+    if jiraClient.Authentication.Authenticated() {
+        client = jiraClient
+    }
+    devCache[issue.Key] = loadDevStatus(client, issue)
+    printIssue(os.Stdout, issue)
+    return nil
+}
+
+func moveCommand(args []string) error {
+
+    positional, err := parseCommand(flag.NewFlagSet("move", flag.ContinueOnError), args, 2)
+    if err != nil {
+        return err
+    }
+    issue, err := fetchIssue(positional[0])
+    if err != nil {
+        return err
+    }
+    if err := transitionTo(jiraClient, issue, positional[1]); err != nil {
+        if errorKindOf(err) == errUnknown {
+            return &jbError{kind: errValidation, err: err}
+        }
+        return err
+    }
+    return nil
+}
+
+func assignCommand(args []string) error {
+
+    positional, err := parseCommand(flag.NewFlagSet("assign", flag.ContinueOnError), args, 2)
+    if err != nil {
+        return err
+    }
+    if err := ensureAuthenticated(); err != nil {
+        return err
+    }
+    res, err := jiraClient.Issue.UpdateAssignee(positional[0], &jira.User{Name: positional[1]})
+    return newJiraError(res, err)
+}
+
+func commentCommand(args []string) error {
+
+    flags := flag.NewFlagSet("comment", flag.ContinueOnError)
+    message := flags.String("m", "", "")
+    positional, err := parseCommand(flags, args, 1)
+    if err != nil {
+        return err
+    }
+
+    body := *message
+    if body == "" {
+        content, err := ioutil.ReadAll(os.Stdin)
+        if err != nil {
+            return err
+        }
+        body = string(content)
+    }
+    body = strings.TrimSpace(body)
+    if body == "" {
+        return usageError("the comment is empty")
+    }
+
+    if err := ensureAuthenticated(); err != nil {
+        return err
+    }
+    _, res, err := jiraClient.Issue.AddComment(positional[0], &jira.Comment{Body: body})
+    return newJiraError(res, err)
+}
+
+func openCommand(args []string) error {
+
+    positional, err := parseCommand(flag.NewFlagSet("open", flag.ContinueOnError), args, 1)
+    if err != nil {
+        return err
+    }
+    conf, err := readConfig()
+    if err != nil {
+        return err
+    }
+    if err := exec.Command(conf.browserCommand, conf.instanceURL+"/browse/"+positional[0]).Start(); err != nil {
+        return &jbError{kind: errConfig, err: err}
+    }
+    return nil
+}
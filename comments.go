@@ -0,0 +1,85 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// issueComments fetches the comments of the issue, falling back to the
+// ones we have seen before when JIRA can't be reached.
+func issueComments(issueKey string) []*jira.Comment {
+
+    err := ensureAuthenticated()
+    if err == nil {
+        issue, res, reqErr := jiraClient.Issue.Get(issueKey, &jira.GetQueryOptions{Fields: "comment"})
+        if reqErr == nil {
+            comments := []*jira.Comment{}
+            if issue.Fields != nil && issue.Fields.Comments != nil {
+                comments = issue.Fields.Comments.Comments
+            }
+            cacheComments(issueKey, comments)
+            return comments
+        }
+        err = newJiraError(res, reqErr)
+    }
+
+    log.Warn("Couldn't fetch comments of " + issueKey + ": " + err.Error())
+    return cache.Comments[issueKey]
+}
+
+// printComments writes the comments of the issue to the preview,
+// including the ones still waiting to be sent.
+func printComments(v io.Writer, issueKey string) {
+
+    comments := issueComments(issueKey)
+    pending := pendingComments(issueKey)
+    if len(comments)+len(pending) == 0 {
+        return
+    }
+
+    fmt.Fprint(v, "\nComments:\n\n")
+    for _, c := range comments {
+        created := c.Created
+        if len(created) >= 16 {
+            created = strings.Replace(created[:16], "T", " ", 1)
+        }
+        fmt.Fprintln(v, c.Author.DisplayName+" ("+created+"):")
+        fmt.Fprintln(v, strings.Replace(c.Body, "\r\n", "\n", -1))
+        fmt.Fprintln(v)
+    }
+    for _, body := range pending {
+        fmt.Fprintln(v, "Not sent yet:")
+        fmt.Fprintln(v, body)
+        fmt.Fprintln(v)
+    }
+}
+
+// sendComment posts the comment, or queues it if we are offline
+func sendComment(g *gocui.Gui, issue jira.Issue, body string) {
+
+    body = strings.TrimSpace(body)
+    if body == "" {
+        updateStatusBar(g, "Comment is empty, nothing is sent")
+        return
+    }
+
+    err := ensureAuthenticated()
+    if err == nil {
+        _, res, reqErr := jiraClient.Issue.AddComment(issue.ID, &jira.Comment{Body: body})
+        err = newJiraError(res, reqErr)
+    }
+
+    switch {
+    case err == nil:
+        updateStatusBar(g, "Comment sent to "+issue.Key)
+    case isOffline(err):
+        queueOp(pendingOp{Kind: "comment", IssueID: issue.ID, IssueKey: issue.Key, Body: body})
+        updateStatusBar(g, "Offline, comment will be sent when JIRA is reachable")
+    default:
+        showError(g, err)
+    }
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "net/url"
+    "os/exec"
+    "sort"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// devEntry is a branch, a commit or a pull request of the issue
+type devEntry struct {
+    title string
+    url   string
+}
+
+// devStatus is the development section of an issue, and where it comes
+// from.
+type devStatus struct {
+    entries []devEntry
+    source  string
+}
+
+var (
+    // previewDevLinks maps the development lines of the preview to their
+    // addresses, like previewAttachments.
+    previewDevLinks = map[string]string{}
+    // devCache keeps the development sections until the board is loaded
+    // again, devLoading the issues being fetched in background. Both
+    // belong to the UI goroutine.
+    devCache   = map[string]devStatus{}
+    devLoading = map[string]bool{}
+)
+
+// devDetail is what the dev-status API tells about one instance, like
+// GitHub or Bitbucket.
+type devDetail struct {
+    Branches []struct {
+        Name       string `json:"name"`
+        URL        string `json:"url"`
+        Repository struct {
+            Name string `json:"name"`
+        } `json:"repository"`
+    } `json:"branches"`
+    PullRequests []struct {
+        ID     string `json:"id"`
+        Name   string `json:"name"`
+        URL    string `json:"url"`
+        Status string `json:"status"`
+        Source struct {
+            Branch string `json:"branch"`
+        } `json:"source"`
+        Destination struct {
+            Branch string `json:"branch"`
+        } `json:"destination"`
+    } `json:"pullRequests"`
+    Repositories []struct {
+        Name    string `json:"name"`
+        Commits []struct {
+            DisplayID string `json:"displayId"`
+            Message   string `json:"message"`
+            URL       string `json:"url"`
+            Author    struct {
+                Name string `json:"name"`
+            } `json:"author"`
+        } `json:"commits"`
+    } `json:"repositories"`
+}
+
+// devInstanceTypes asks which development tools know about the issue
+func devInstanceTypes(client *jira.Client, issue jira.Issue) ([]string, error) {
+
+    req, err := client.NewRequest("GET", "rest/dev-status/1.0/issue/summary?issueId="+url.QueryEscape(issue.ID), nil)
+    if err != nil {
+        return nil, err
+    }
+    result := struct {
+        Summary map[string]struct {
+            ByInstanceType map[string]interface{} `json:"byInstanceType"`
+        } `json:"summary"`
+    }{}
+    if res, err := client.Do(req, &result); err != nil {
+        return nil, newJiraError(res, err)
+    }
+
+    found := map[string]bool{}
+    for _, data := range result.Summary {
+        for instanceType := range data.ByInstanceType {
+            found[instanceType] = true
+        }
+    }
+    instanceTypes := []string{}
+    for instanceType := range found {
+        instanceTypes = append(instanceTypes, instanceType)
+    }
+    sort.Strings(instanceTypes)
+    return instanceTypes, nil
+}
+
+// fetchDevStatus gets the branches, commits and pull requests JIRA knows
+// of, with a logged in client. The dev-status API is not documented, JIRA
+// without a connected development tool answers with an error.
+func fetchDevStatus(client *jira.Client, issue jira.Issue) ([]devEntry, error) {
+
+    instanceTypes, err := devInstanceTypes(client, issue)
+    if err != nil {
+        return nil, err
+    }
+
+    pullRequests, branches, commits := []devEntry{}, []devEntry{}, []devEntry{}
+    for _, instanceType := range instanceTypes {
+        for _, dataType := range []string{"pullrequest", "repository"} {
+            req, err := client.NewRequest("GET", fmt.Sprintf(
+                "rest/dev-status/1.0/issue/detail?issueId=%s&applicationType=%s&dataType=%s",
+                url.QueryEscape(issue.ID), url.QueryEscape(instanceType), dataType), nil)
+            if err != nil {
+                return nil, err
+            }
+            result := struct {
+                Detail []devDetail `json:"detail"`
+            }{}
+            if res, err := client.Do(req, &result); err != nil {
+                return nil, newJiraError(res, err)
+            }
+
+            for _, detail := range result.Detail {
+                for _, pr := range detail.PullRequests {
+                    title := fmt.Sprintf("PR %s [%s] %s (%s -> %s)", pr.ID, pr.Status, pr.Name, pr.Source.Branch, pr.Destination.Branch)
+                    pullRequests = append(pullRequests, devEntry{title, pr.URL})
+                }
+                for _, branch := range detail.Branches {
+                    branches = append(branches, devEntry{"Branch " + branch.Name + " (" + branch.Repository.Name + ")", branch.URL})
+                }
+                for _, repository := range detail.Repositories {
+                    for _, commit := range repository.Commits {
+                        title := "Commit " + commit.DisplayID + " " + firstLine(commit.Message) + " (" + commit.Author.Name + ")"
+                        commits = append(commits, devEntry{title, commit.URL})
+                    }
+                }
+            }
+        }
+    }
+    return append(append(pullRequests, branches...), commits...), nil
+}
+
+func firstLine(text string) string {
+    return strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+}
+
+// remoteWebURL guesses the web page of the origin remote, which works for
+// GitHub, GitLab and the like.
+func remoteWebURL() string {
+    remote, err := git("remote", "get-url", "origin")
+    if err != nil || remote == "" {
+        return ""
+    }
+    remote = strings.TrimSuffix(remote, ".git")
+    switch {
+    case strings.HasPrefix(remote, "https://"), strings.HasPrefix(remote, "http://"):
+        if parsed, err := url.Parse(remote); err == nil {
+            parsed.User = nil
+            return parsed.String()
+        }
+    case strings.HasPrefix(remote, "ssh://"):
+        if parsed, err := url.Parse(remote); err == nil {
+            return "https://" + parsed.Hostname() + parsed.Path
+        }
+    case strings.Contains(remote, ":"):
+        // git@host:group/repository
+        parts := strings.SplitN(remote, ":", 2)
+        host := parts[0][strings.LastIndex(parts[0], "@")+1:]
+        return "https://" + host + "/" + strings.TrimPrefix(parts[1], "/")
+    }
+    return ""
+}
+
+// localDevStatus scans the local repository for the issue key, when JIRA
+// has nothing to tell. The key is followed by a non-digit so TECH-1 doesn't
+// find TECH-12. Branches are linked only if they were pushed.
+func localDevStatus(issue jira.Issue) []devEntry {
+
+    entries := []devEntry{}
+    if !inGitRepository() {
+        return entries
+    }
+    web := remoteWebURL()
+    link := func(path string) string {
+        if web == "" {
+            return ""
+        }
+        return web + path
+    }
+
+    branches, err := git("for-each-ref", "--format=%(refname:short)", "refs/heads")
+    if err != nil {
+        return entries
+    }
+    for _, branch := range strings.Split(branches, "\n") {
+        if indexOf(issue.Key, issueKeyPattern.FindAllString(branch, -1)) < 0 {
+            continue
+        }
+        entry := devEntry{"Branch " + branch + " (local)", ""}
+        if _, err := git("rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch); err == nil {
+            entry = devEntry{"Branch " + branch, link("/tree/" + branch)}
+        }
+        entries = append(entries, entry)
+    }
+    commits, err := git("log", "--all", "--extended-regexp", "--grep="+issue.Key+"([^0-9]|$)", "-n", "50", "--format=%H%x09%h%x09%s%x09%an")
+    if err != nil || commits == "" {
+        return entries
+    }
+    for _, line := range strings.Split(commits, "\n") {
+        fields := strings.SplitN(line, "\t", 4)
+        if len(fields) < 4 {
+            continue
+        }
+        entries = append(entries, devEntry{"Commit " + fields[1] + " " + fields[2] + " (" + fields[3] + ")", link("/commit/" + fields[0])})
+    }
+    return entries
+}
+
+// loadDevStatus finds what was done for the issue in the code, from JIRA
+// if it knows, from the local repository otherwise. The client is nil
+// when JIRA can't be asked.
+func loadDevStatus(client *jira.Client, issue jira.Issue) devStatus {
+    if client != nil {
+        entries, err := fetchDevStatus(client, issue)
+        if err == nil && len(entries) > 0 {
+            return devStatus{entries, ""}
+        }
+        if err != nil {
+            log.Debug("No development status from JIRA: ", err)
+        }
+    }
+    return devStatus{localDevStatus(issue), " (from the local git log)"}
+}
+
+// loadDevStatusInBackground fetches the development section without
+// holding up the preview, and adds it to the end once it is there.
+func loadDevStatusInBackground(g *gocui.Gui, issue jira.Issue) {
+
+    if devLoading[issue.Key] {
+        return
+    }
+    devLoading[issue.Key] = true
+
+    // Logging in replaces jiraClient, it can't happen in the goroutine
+    var client *jira.Client
+    if ensureAuthenticated() == nil {
+        client = jiraClient
+    }
+    go func() {
+        status := loadDevStatus(client, issue)
+        g.Update(func(g *gocui.Gui) error {
+            delete(devLoading, issue.Key)
+            devCache[issue.Key] = status
+            v, err := g.View("previewBox")
+            if err != nil || previewed.Key != issue.Key {
+                return nil
+            }
+            printDevelopment(v, status)
+            return nil
+        })
+    }()
+}
+
+// printDevelopment writes the development section, if there is something
+// in it.
+func printDevelopment(v io.Writer, status devStatus) {
+
+    if len(status.entries) == 0 {
+        return
+    }
+    fmt.Fprint(v, "\nDevelopment"+status.source+":\n")
+    for _, entry := range status.entries {
+        line := "  " + entry.title
+        if entry.url != "" {
+            previewDevLinks[line] = entry.url
+        }
+        fmt.Fprintln(v, line)
+    }
+}
+
+// devLinkUnderCursor finds the address of the highlighted development line
+func devLinkUnderCursor(v *gocui.View) string {
+    _, cy := v.Cursor()
+    line, err := v.Line(cy)
+    if err != nil {
+        return ""
+    }
+    return previewDevLinks[line]
+}
+
+func openDevLink(g *gocui.Gui, link string) error {
+    conf, err := readConfig()
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    if err := exec.Command(conf.browserCommand, link).Start(); err != nil {
+        showError(g, &jbError{kind: errConfig, err: err})
+        return nil
+    }
+    updateStatusBar(g, "Opened "+link)
+    return nil
+}
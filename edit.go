@@ -0,0 +1,167 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// editFormFields are the fields we let the user change, if JIRA allows
+var editFormFields = []string{
+    "summary",
+    "description",
+    "labels",
+    "components",
+    "priority",
+    "duedate",
+    "fixVersions",
+}
+
+// getEditMeta fetches which fields of the issue can be changed
+func getEditMeta(issueKey string) (map[string]fieldMeta, error) {
+
+    if err := ensureAuthenticated(); err != nil {
+        return nil, err
+    }
+
+    req, err := jiraClient.NewRequest("GET", fmt.Sprintf("rest/api/2/issue/%s/editmeta", issueKey), nil)
+    if err != nil {
+        return nil, err
+    }
+
+    result := struct {
+        Fields map[string]fieldMeta `json:"fields"`
+    }{}
+    if res, err := jiraClient.Do(req, &result); err != nil {
+        return nil, newJiraError(res, err)
+    }
+    return result.Fields, nil
+}
+
+// currentFieldValue gives the value of the field as it is shown on the
+// form, so the user edits what is there already.
+func currentFieldValue(issue jira.Issue, id string) (string, string) {
+    fields := issue.Fields
+    switch id {
+    case "summary":
+        return fields.Summary, ""
+    case "description":
+        return strings.Replace(fields.Description, "\r\n", "\n", -1), ""
+    case "labels":
+        return strings.Join(fields.Labels, ", "), ""
+    case "components":
+        names := []string{}
+        for _, c := range fields.Components {
+            names = append(names, c.Name)
+        }
+        return strings.Join(names, ", "), ""
+    case "fixVersions":
+        names := []string{}
+        for _, v := range fields.FixVersions {
+            names = append(names, v.Name)
+        }
+        return strings.Join(names, ", "), ""
+    case "priority":
+        if fields.Priority != nil {
+            return fields.Priority.Name, fields.Priority.ID
+        }
+    case "duedate":
+        if due := time.Time(fields.Duedate); !due.IsZero() {
+            return due.Format("2006-01-02"), ""
+        }
+    }
+    return "", ""
+}
+
+func editIssueHandler(g *gocui.Gui, v *gocui.View) error {
+    currentColumn, err := getColumn(active.columnname)
+    if err != nil || len(currentColumn.members) == 0 {
+        return nil
+    }
+    return openEditForm(g, currentColumn.members[active.indexno].issue)
+}
+
+func openEditForm(g *gocui.Gui, issue jira.Issue) error {
+
+    metas, err := getEditMeta(issue.Key)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    editable := map[string]fieldMeta{}
+    for _, id := range editFormFields {
+        if meta, ok := metas[id]; ok {
+            editable[id] = meta
+        }
+    }
+    if len(editable) == 0 {
+        showError(g, &jbError{kind: errPermission, err: fmt.Errorf("You can't edit %s", issue.Key)})
+        return nil
+    }
+
+    fields := formFieldsFromMeta(editable)
+    original := map[string]string{}
+    for i := range fields {
+        fields[i].value, fields[i].valueID = currentFieldValue(issue, fields[i].id)
+        original[fields[i].id] = fields[i].value
+    }
+
+    return openForm(g, &fieldForm{
+        title:  "Edit " + issue.Key,
+        fields: fields,
+        submit: func(g *gocui.Gui, form *fieldForm) error {
+            changed := map[string]interface{}{}
+            for _, f := range form.fields {
+                if f.value == original[f.id] {
+                    continue
+                }
+                if f.value == "" {
+                    changed[f.id] = emptyFieldValue(f)
+                    continue
+                }
+                value, err := fieldValue(f)
+                if err != nil {
+                    return err
+                }
+                changed[f.id] = value
+            }
+            return updateJiraIssue(g, issue.Key, changed)
+        },
+    })
+}
+
+// emptyFieldValue is how a field is cleared
+func emptyFieldValue(f formField) interface{} {
+    if f.meta.Schema.Type == "array" {
+        return []interface{}{}
+    }
+    return nil
+}
+
+// updateJiraIssue sends the changed fields and redraws the card
+func updateJiraIssue(g *gocui.Gui, issueKey string, changed map[string]interface{}) error {
+
+    if len(changed) == 0 {
+        updateStatusBar(g, "Nothing changed on "+issueKey)
+        return nil
+    }
+
+    if res, err := jiraClient.Issue.UpdateIssue(issueKey, map[string]interface{}{"fields": changed}); err != nil {
+        return newJiraError(res, err)
+    }
+
+    issue, res, err := jiraClient.Issue.Get(issueKey, nil)
+    if err != nil {
+        showError(g, newJiraError(res, err))
+        return nil
+    }
+    redrawCard(g, *issue)
+    saveBoardCache()
+
+    updateStatusBar(g, issueKey+" is updated")
+    return nil
+}
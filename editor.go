@@ -0,0 +1,125 @@
+package main
+
+import (
+    "errors"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "strings"
+
+    "github.com/jroimartin/gocui"
+    "github.com/nsf/termbox-go"
+)
+
+// scissors separates the text from our hints in the editor, everything
+// below it is thrown away.
+var scissors = "# ------------------------ >8 ------------------------"
+
+// outputMode is needed again when we take the terminal back
+var outputMode = gocui.OutputNormal
+
+// editorCommand picks the editor: the configured one, then the usual
+// environment variables.
+func editorCommand() string {
+    if conf, err := readConfig(); err == nil && conf.editorCommand != "" {
+        return conf.editorCommand
+    }
+    for _, name := range []string{"VISUAL", "EDITOR"} {
+        if editor := os.Getenv(name); editor != "" {
+            return editor
+        }
+    }
+    return "vi"
+}
+
+// resumeTerminal takes the terminal back after the editor and asks
+// gocui to draw everything again.
+func resumeTerminal(g *gocui.Gui) error {
+    if err := termbox.Init(); err != nil {
+        return err
+    }
+    inputMode := termbox.InputEsc
+    if g.Mouse {
+        inputMode |= termbox.InputMouse
+    }
+    termbox.SetInputMode(inputMode)
+    termbox.SetOutputMode(termbox.OutputMode(outputMode))
+    g.Update(func(g *gocui.Gui) error {
+        return nil
+    })
+    return nil
+}
+
+// runEditor suspends the UI and lets the user edit the text in a real
+// editor. The hint is written below the scissors line.
+func runEditor(g *gocui.Gui, text string, hint string) (string, error) {
+
+    file, err := ioutil.TempFile("", "jb-*.txt")
+    if err != nil {
+        return "", err
+    }
+    defer os.Remove(file.Name())
+
+    content := text + "\n\n" + scissors + "\n"
+    for _, line := range strings.Split(hint, "\n") {
+        content = content + "# " + line + "\n"
+    }
+    _, err = file.WriteString(content)
+    file.Close()
+    if err != nil {
+        return "", err
+    }
+
+    args := strings.Fields(editorCommand())
+    cmd := exec.Command(args[0], append(args[1:], file.Name())...)
+    cmd.Stdin = os.Stdin
+    cmd.Stdout = os.Stdout
+    cmd.Stderr = os.Stderr
+
+    termbox.Close()
+    runErr := cmd.Run()
+    if err := resumeTerminal(g); err != nil {
+        return "", err
+    }
+    if runErr != nil {
+        return "", &jbError{kind: errConfig, err: errors.New("Editor failed: " + runErr.Error())}
+    }
+
+    edited, err := ioutil.ReadFile(file.Name())
+    if err != nil {
+        return "", err
+    }
+    result := strings.SplitN(string(edited), scissors, 2)[0]
+    return strings.TrimSpace(result), nil
+}
+
+// commentInEditor moves the comment being typed into the editor, and
+// sends what comes back.
+func commentInEditor(g *gocui.Gui, v *gocui.View, issueKey string, send func(g *gocui.Gui, body string)) error {
+
+    text := strings.TrimSpace(v.Buffer())
+    body, err := runEditor(g, text, "Comment on "+issueKey+"\nEverything below the line above is ignored.\nAn empty comment is not sent.")
+    destroyView(g, v)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    send(g, body)
+    return nil
+}
+
+// formInputInEditor edits the value of the form field in the editor
+func formInputInEditor(g *gocui.Gui, v *gocui.View) error {
+
+    field := &activeForm.fields[activeForm.editing]
+    value, err := runEditor(g, strings.TrimSpace(v.Buffer()), field.meta.Name+" of "+activeForm.title+"\nEverything below the line above is ignored.")
+    destroyView(g, v)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    field.value = value
+    redrawForm(g)
+    return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// epicLinkType is how JIRA Software marks its epic link field
+var epicLinkType = "com.pyxis.greenhopper.jira:gh-epic-link"
+
+var (
+    customFieldsResolved = false
+    colorByEpic          = false
+    epicFilter           = ""
+    hiddenIssues         = []jira.Issue{}
+)
+
+// resolveCustomFields finds the ids of the epic link and the flag
+// fields. They are custom fields, so every instance has different ones.
+func resolveCustomFields(conf configItem) {
+
+    if conf.epicLinkField != "" {
+        cache.EpicLinkField = conf.epicLinkField
+    }
+    if customFieldsResolved || (cache.EpicLinkField != "" && cache.FlagField != "") {
+        return
+    }
+
+    fields, res, err := jiraClient.Field.GetList()
+    if err != nil {
+        log.Warn("Couldn't list the fields: " + newJiraError(res, err).Error())
+        return
+    }
+    customFieldsResolved = true
+    for _, field := range fields {
+        if cache.EpicLinkField == "" && field.Schema.Custom == epicLinkType {
+            cache.EpicLinkField = field.ID
+        }
+        if cache.FlagField == "" && field.Custom && field.Name == flagFieldName {
+            cache.FlagField = field.ID
+        }
+    }
+}
+
+// epicOf gives the key of the epic of the issue, or its parent if it is
+// a subtask.
+func epicOf(issue jira.Issue) string {
+    if issue.Fields == nil {
+        return ""
+    }
+    if cache.EpicLinkField != "" {
+        if key, ok := issue.Fields.Unknowns[cache.EpicLinkField].(string); ok && key != "" {
+            return key
+        }
+    }
+    if issue.Fields.Parent != nil {
+        return issue.Fields.Parent.Key
+    }
+    return ""
+}
+
+func epicName(epicKey string) string {
+    if name, ok := cache.Epics[epicKey]; ok && name != "" {
+        return name
+    }
+    return epicKey
+}
+
+// resolveEpics finds the names of the epics the issues belong to. The
+// ones on the board are known already, the rest is asked in one query.
+func resolveEpics(issues []jira.Issue) {
+
+    summaries := map[string]string{}
+    for _, issue := range issues {
+        summaries[issue.Key] = issue.Fields.Summary
+    }
+    missing := []string{}
+    for _, issue := range issues {
+        epicKey := epicOf(issue)
+        if epicKey == "" {
+            continue
+        }
+        if summary, ok := summaries[epicKey]; ok {
+            cache.Epics[epicKey] = summary
+        } else if _, ok := cache.Epics[epicKey]; !ok && indexOf(epicKey, missing) < 0 {
+            missing = append(missing, epicKey)
+        }
+    }
+    if len(missing) == 0 {
+        return
+    }
+
+    epics, res, err := jiraClient.Issue.Search(
+        "key in ("+strings.Join(missing, ",")+")",
+        &jira.SearchOptions{Fields: []string{"summary"}, MaxResults: len(missing)},
+    )
+    if err != nil {
+        log.Warn("Couldn't fetch the epics: " + newJiraError(res, err).Error())
+        return
+    }
+    for _, epic := range epics {
+        cache.Epics[epic.Key] = epic.Fields.Summary
+    }
+}
+
+func inEpicFilter(issue jira.Issue) bool {
+    return epicFilter == "" || epicOf(issue) == epicFilter
+}
+
+// epicMarker is shown in the status bar while the board is filtered
+func epicMarker() string {
+    if epicFilter == "" {
+        return ""
+    }
+    return "[epic " + epicFilter + "] "
+}
+
+// progressBar draws done out of total as a bar of the given width
+func progressBar(done int, total int, width int) string {
+    filled := 0
+    if total > 0 {
+        filled = done * width / total
+    }
+    return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// countDone counts the issues of each epic, and the done ones of them
+func countDone(issues []jira.Issue, done map[string]int, total map[string]int) {
+    for _, issue := range issues {
+        epicKey := epicOf(issue)
+        if _, ok := total[epicKey]; !ok {
+            continue
+        }
+        total[epicKey]++
+        if issue.Fields.Status != nil && issue.Fields.Status.StatusCategory.Key == "done" {
+            done[epicKey]++
+        }
+    }
+}
+
+// epicProgress counts the children of the epics in one query, the board
+// query leaves the finished ones out. The board is counted if JIRA can't
+// be asked.
+func epicProgress(epics []string) (map[string]int, map[string]int) {
+
+    done := map[string]int{}
+    total := map[string]int{}
+    reset := func() {
+        for _, epicKey := range epics {
+            done[epicKey] = 0
+            total[epicKey] = 0
+        }
+    }
+    reset()
+
+    keys := strings.Join(epics, ",")
+    query := "parent in (" + keys + ")"
+    fields := []string{"status", "parent"}
+    if cache.EpicLinkField != "" {
+        query = `"Epic Link" in (` + keys + ") OR " + query
+        fields = append(fields, cache.EpicLinkField)
+    }
+
+    children := []jira.Issue{}
+    err := ensureAuthenticated()
+    if err == nil {
+        err = jiraClient.Issue.SearchPages(query, &jira.SearchOptions{Fields: fields, MaxResults: 100}, func(issue jira.Issue) error {
+            children = append(children, issue)
+            return nil
+        })
+    }
+    if err != nil {
+        log.Warn("Couldn't count the issues of the epics: " + err.Error())
+        reset()
+        countDone(boardIssues(), done, total)
+        return done, total
+    }
+    countDone(children, done, total)
+    return done, total
+}
+
+// openEpicPanel lists the epics of the board with how many of their
+// issues are done. Picking one shows only its issues.
+func openEpicPanel(g *gocui.Gui, v *gocui.View) error {
+
+    epics := []string{}
+    found := map[string]bool{}
+    for _, issue := range boardIssues() {
+        epicKey := epicOf(issue)
+        if epicKey != "" && !found[epicKey] {
+            found[epicKey] = true
+            epics = append(epics, epicKey)
+        }
+    }
+    if len(epics) == 0 {
+        updateStatusBar(g, "No issue on the board belongs to an epic")
+        return nil
+    }
+    sort.Strings(epics)
+    done, total := epicProgress(epics)
+
+    allIssues := "(all issues)"
+    options := []string{allIssues}
+    lines := map[string]string{}
+    for _, epicKey := range epics {
+        name := []rune(epicName(epicKey))
+        if len(name) > 18 {
+            name = append(name[:17], '~')
+        }
+        line := fmt.Sprintf("%-10s %-18s %s %d/%d", epicKey, string(name), progressBar(done[epicKey], total[epicKey], 8), done[epicKey], total[epicKey])
+        lines[line] = epicKey
+        options = append(options, line)
+    }
+
+    return openPicker(g, "Epics, done/total", options, func(g *gocui.Gui, choice string) error {
+        epicFilter = lines[choice]
+        if err := placeIssues(g, boardIssues()); err != nil {
+            return err
+        }
+        updateStatusBar(g, infoText)
+        return nil
+    })
+}
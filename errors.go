@@ -0,0 +1,147 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+type errorKind int
+
+const (
+    errUnknown errorKind = iota
+    errAuth
+    errNetwork
+    errPermission
+    errValidation
+    errConfig
+    errUsage
+)
+
+func (k errorKind) String() string {
+    switch k {
+    case errAuth:
+        return "Authentication failed"
+    case errNetwork:
+        return "Couldn't reach JIRA"
+    case errPermission:
+        return "Permission denied"
+    case errValidation:
+        return "JIRA refused the request"
+    case errConfig:
+        return "Configuration problem"
+    case errUsage:
+        return "Wrong usage"
+    }
+    return "Something went wrong"
+}
+
+// jbError is what we show to the user, it tells what kind of problem
+// happened along with the original error.
+type jbError struct {
+    kind errorKind
+    err  error
+}
+
+func (e *jbError) Error() string {
+    return e.kind.String() + ": " + e.message()
+}
+
+func (e *jbError) Unwrap() error {
+    return e.err
+}
+
+// message prefers the messages JIRA sent over the HTTP error
+func (e *jbError) message() string {
+    var jiraErr *jira.Error
+    if !errors.As(e.err, &jiraErr) {
+        return e.err.Error()
+    }
+
+    messages := append([]string{}, jiraErr.ErrorMessages...)
+    fields := make([]string, 0, len(jiraErr.Errors))
+    for field := range jiraErr.Errors {
+        fields = append(fields, field)
+    }
+    sort.Strings(fields)
+    for _, field := range fields {
+        messages = append(messages, field+": "+jiraErr.Errors[field])
+    }
+    if len(messages) == 0 {
+        return jiraErr.Error()
+    }
+    return strings.Join(messages, "; ")
+}
+
+// newJiraError decides the kind of the error by the response of JIRA.
+// No response at all means we couldn't talk to the server.
+func newJiraError(res *jira.Response, err error) error {
+    if err == nil {
+        return nil
+    }
+
+    var jbErr *jbError
+    if errors.As(err, &jbErr) {
+        return err
+    }
+
+    kind := errNetwork
+    if res != nil && res.Response != nil {
+        switch code := res.StatusCode; {
+        case code == http.StatusUnauthorized:
+            kind = errAuth
+        case code == http.StatusForbidden:
+            kind = errPermission
+        case code >= 400 && code < 500:
+            kind = errValidation
+        case code >= 500:
+            kind = errNetwork
+        default:
+            kind = errUnknown
+        }
+    }
+
+    return &jbError{kind: kind, err: err}
+}
+
+func errorKindOf(err error) errorKind {
+    var jbErr *jbError
+    if errors.As(err, &jbErr) {
+        return jbErr.kind
+    }
+    return errUnknown
+}
+
+// showError opens a dialog for the error, which can be dismissed
+// without losing the board.
+func showError(g *gocui.Gui, err error) {
+
+    log.Warn(err)
+
+    title := errUnknown.String()
+    message := err.Error()
+    var jbErr *jbError
+    if errors.As(err, &jbErr) {
+        title = jbErr.kind.String()
+        message = jbErr.message()
+    }
+
+    maxX, maxY := g.Size()
+    v, viewErr := g.SetView("errorBox", maxX/2-35, maxY/2-3, maxX/2+35, maxY/2+3)
+    if viewErr != nil && viewErr != gocui.ErrUnknownView {
+        updateStatusBar(g, err.Error())
+        return
+    }
+    v.Clear()
+    v.Wrap = true
+    v.Title = title
+    fmt.Fprintln(v, message)
+
+    setCurrentViewOnTop(g, "errorBox")
+    updateStatusBar(g, "Dismiss: "+keyHint("select")+" or "+keyHint("close"))
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+    "fmt"
+    "html/template"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// snapshot is the board as it is exported
+type snapshot struct {
+    query  string
+    sprint string
+    epic   string
+    taken  time.Time
+    groups []boardGroup
+}
+
+var snapshotFields = []string{"key", "summary", "assignee", "priority", "epic", "components", "labels"}
+
+// htmlCard and htmlColumn are what the page template gets
+type htmlCard struct {
+    Key, URL, Summary, Assignee, Priority, PriorityColor, Epic, EpicColor string
+    Components                                                           []htmlLabel
+    Labels                                                               []string
+    Flagged, Blocked                                                     bool
+}
+
+type htmlLabel struct {
+    Name, Color string
+}
+
+type htmlColumn struct {
+    Name  string
+    Cards []htmlCard
+}
+
+var snapshotPage = template.Must(template.New("snapshot").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Board snapshot {{.Taken.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: sans-serif; margin: 0; color: {{or .Colors.Frame "#222"}}; background: {{or .Colors.Background "#fff"}}; }
+header { padding: 8px 12px; color: {{or .Colors.Status "#fff"}}; background: {{or .Colors.StatusBackground "#8b008b"}}; }
+header p { margin: 2px 0; font-size: 90%; }
+.board { display: flex; gap: 8px; padding: 8px; align-items: flex-start; }
+.column { flex: 1; min-width: 0; border: 1px solid {{or .Colors.Frame "#888"}}; }
+.column h2 { font-size: 100%; margin: 0; padding: 6px; border-bottom: 1px solid {{or .Colors.Frame "#888"}}; }
+.card { margin: 6px; padding: 6px; border: 1px solid {{or .Colors.Frame "#888"}}; font-size: 90%; }
+.card a { font-weight: bold; color: inherit; }
+.meta { opacity: 0.8; }
+.flag { color: #cdcd00; } .blocked { color: #cd0000; }
+</style>
+</head>
+<body>
+<header>
+<strong>Board snapshot</strong>
+<p>Taken {{.Taken.Format "2006-01-02 15:04"}}</p>
+<p>Query: {{.Query}}</p>
+{{if .Sprint}}<p>Sprint: {{.Sprint}}</p>{{end}}
+{{if .Epic}}<p>Epic: {{.Epic}}</p>{{end}}
+</header>
+<div class="board">
+{{range .Columns}}<div class="column">
+<h2>{{.Name}} ({{len .Cards}})</h2>
+{{range .Cards}}<div class="card">
+{{if .Components}}<div>[{{range $i, $c := .Components}}{{if $i}} {{end}}<span style="color: {{$c.Color}}">{{$c.Name}}</span>{{end}}]</div>{{end}}
+{{if .Epic}}<div style="color: {{.EpicColor}}">&lt;{{.Epic}}&gt;</div>{{end}}
+<div><a href="{{.URL}}">{{.Key}}</a> {{if .Flagged}}<span class="flag">[flag]</span> {{end}}{{if .Blocked}}<span class="blocked">[blocked]</span> {{end}}{{.Summary}}</div>
+<div class="meta">{{.Assignee}}{{if .Priority}} · <span style="color: {{.PriorityColor}}">{{.Priority}}</span>{{end}}{{range .Labels}} · {{.}}{{end}}</div>
+</div>
+{{end}}</div>
+{{end}}</div>
+</body>
+</html>
+`))
+
+// boardSnapshot is the board as it is shown, with the epic filter
+func boardSnapshot(conf configItem) snapshot {
+    snap := snapshot{query: boardQuery(conf), taken: time.Now()}
+    if cache.Sprint.ID != 0 {
+        snap.sprint = sprintLabel(cache.Sprint)
+    }
+    if epicFilter != "" {
+        snap.epic = epicFilter + " " + epicName(epicFilter)
+    }
+    for i := range kanbanMatrix {
+        group := boardGroup{name: kanbanMatrix[i].view.Title, issues: []jira.Issue{}}
+        for _, box := range kanbanMatrix[i].members {
+            group.issues = append(group.issues, box.issue)
+        }
+        snap.groups = append(snap.groups, group)
+    }
+    return snap
+}
+
+func htmlCardOf(conf configItem, issue jira.Issue) htmlCard {
+    card := htmlCard{
+        Key:      issue.Key,
+        URL:      conf.instanceURL + "/browse/" + issue.Key,
+        Summary:  issue.Fields.Summary,
+        Assignee: outputFields["assignee"](issue),
+        Priority: outputFields["priority"](issue),
+        Labels:   issue.Fields.Labels,
+        Flagged:  isFlagged(issue),
+        Blocked:  isBlocked(issue),
+    }
+    if color, ok := theme.priorities[strings.ToLower(card.Priority)]; ok {
+        card.PriorityColor = color.hex()
+    }
+    if epicKey := epicOf(issue); epicKey != "" {
+        card.Epic = epicName(epicKey)
+        if colorByEpic {
+            card.EpicColor = hashColor(epicKey).hex()
+        }
+    }
+    for _, component := range issue.Fields.Components {
+        card.Components = append(card.Components, htmlLabel{component.Name, componentThemeColor(component.Name).hex()})
+    }
+    return card
+}
+
+// writeSnapshot writes the board as a page or as markdown
+func writeSnapshot(w io.Writer, format string, conf configItem, snap snapshot) error {
+
+    if format == "markdown" {
+        fmt.Fprintf(w, "# Board snapshot %s\n\n", snap.taken.Format("2006-01-02 15:04"))
+        fmt.Fprintf(w, "- Query: `%s`\n", snap.query)
+        if snap.sprint != "" {
+            fmt.Fprintf(w, "- Sprint: %s\n", snap.sprint)
+        }
+        if snap.epic != "" {
+            fmt.Fprintf(w, "- Epic: %s\n", snap.epic)
+        }
+        fmt.Fprintln(w)
+        writeMarkdown(w, snap.groups, snapshotFields)
+        return nil
+    }
+
+    columns := []htmlColumn{}
+    for _, group := range snap.groups {
+        column := htmlColumn{Name: group.name}
+        for _, issue := range group.issues {
+            column.Cards = append(column.Cards, htmlCardOf(conf, issue))
+        }
+        columns = append(columns, column)
+    }
+    return snapshotPage.Execute(w, map[string]interface{}{
+        "Taken":   snap.taken,
+        "Query":   snap.query,
+        "Sprint":  snap.sprint,
+        "Epic":    snap.epic,
+        "Columns": columns,
+        "Colors": map[string]string{
+            "Frame":            theme.frame.hex(),
+            "Background":       theme.background.hex(),
+            "Status":           theme.status.hex(),
+            "StatusBackground": theme.statusBackground.hex(),
+        },
+    })
+}
+
+// snapshotFormat decides the format by the extension of the file
+func snapshotFormat(path string) string {
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".md", ".markdown":
+        return "markdown"
+    }
+    return "html"
+}
+
+func saveSnapshot(path string, conf configItem, snap snapshot) error {
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    if err := writeSnapshot(file, snapshotFormat(path), conf, snap); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
+
+func exportBoardHandler(g *gocui.Gui, v *gocui.View) error {
+    return openPicker(g, "Export the board as", []string{"HTML", "Markdown"}, func(g *gocui.Gui, choice string) error {
+
+        conf, err := readConfig()
+        if err != nil {
+            showError(g, err)
+            return nil
+        }
+        extension := ".html"
+        if choice == "Markdown" {
+            extension = ".md"
+        }
+        dir := downloadDir()
+        if err := os.MkdirAll(dir, 0700); err != nil {
+            showError(g, err)
+            return nil
+        }
+        path := filepath.Join(dir, "jb-board-"+time.Now().Format("2006-01-02-1504")+extension)
+        if err := saveSnapshot(path, conf, boardSnapshot(conf)); err != nil {
+            showError(g, err)
+            return nil
+        }
+        updateStatusBar(g, "Board is exported to "+path)
+        return nil
+    })
+}
+
+// exportBoard writes the snapshot of the board for "jb -export", with
+// the issues of the query in the active sprint.
+func exportBoard(path string) error {
+
+    conf, err := readConfig()
+    if err != nil {
+        return err
+    }
+    if err := loadTheme(conf.theme); err != nil {
+        return err
+    }
+    loadCache()
+    if conf.boardID != 0 {
+        cache.Sprint = jira.Sprint{}
+        if err := loadSprints(conf); err != nil {
+            return err
+        }
+    }
+    issues, err := searchAll(boardQuery(conf))
+    if err != nil {
+        return err
+    }
+    resolveCustomFields(conf)
+    resolveEpics(issues)
+
+    snap := snapshot{query: boardQuery(conf), taken: time.Now(), groups: groupIssues(issues)}
+    if cache.Sprint.ID != 0 {
+        snap.sprint = sprintLabel(cache.Sprint)
+    }
+    return saveSnapshot(path, conf, snap)
+}
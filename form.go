@@ -0,0 +1,313 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/jroimartin/gocui"
+)
+
+// jiraDateTimeLayout is how JIRA reads the values of datetime fields
+var jiraDateTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// fieldMeta is the field description JIRA gives us in transition,
+// create and edit metadata. All of them share the same shape.
+type fieldMeta struct {
+    Required        bool           `json:"required"`
+    Name            string         `json:"name"`
+    HasDefaultValue bool           `json:"hasDefaultValue"`
+    Schema          fieldSchema    `json:"schema"`
+    AllowedValues   []allowedValue `json:"allowedValues"`
+    Operations      []string       `json:"operations"`
+}
+
+type fieldSchema struct {
+    Type   string `json:"type"`
+    Items  string `json:"items"`
+    System string `json:"system"`
+    Custom string `json:"custom"`
+}
+
+type allowedValue struct {
+    ID    string `json:"id"`
+    Key   string `json:"key"`
+    Name  string `json:"name"`
+    Value string `json:"value"`
+}
+
+func (a allowedValue) label() string {
+    for _, s := range []string{a.Name, a.Value, a.Key} {
+        if s != "" {
+            return s
+        }
+    }
+    return a.ID
+}
+
+type formField struct {
+    id      string
+    meta    fieldMeta
+    value   string
+    valueID string
+}
+
+// fieldForm is a small popup listing fields, which are filled one by
+// one and handed over to submit at the end. If submit fails, the form
+// is shown again.
+type fieldForm struct {
+    title   string
+    fields  []formField
+    editing int
+    submit  func(g *gocui.Gui, form *fieldForm) error
+}
+
+var (
+    activeForm   = &fieldForm{}
+    formInfoText = "Edit field: Enter  |  Submit: Ctrl-S  |  Cancel: Esc  |  Required fields are marked with *"
+)
+
+// formFieldsFromMeta orders the fields, required ones come first
+func formFieldsFromMeta(metas map[string]fieldMeta) []formField {
+    fields := []formField{}
+    for id, meta := range metas {
+        fields = append(fields, formField{id: id, meta: meta})
+    }
+    sort.Slice(fields, func(i, j int) bool {
+        if fields[i].meta.Required != fields[j].meta.Required {
+            return fields[i].meta.Required
+        }
+        return fields[i].meta.Name < fields[j].meta.Name
+    })
+    return fields
+}
+
+func openForm(g *gocui.Gui, form *fieldForm) error {
+
+    activeForm = form
+
+    maxX, maxY := g.Size()
+    height := len(form.fields) + 1
+    if height > maxY-4 {
+        height = maxY - 4
+    }
+
+    if v, err := g.SetView("form", maxX/2-35, maxY/2-height/2-1, maxX/2+35, maxY/2+height/2+1); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Editable = false
+        v.Highlight = true
+        v.Title = form.title
+    }
+
+    redrawForm(g)
+    setCurrentViewOnTop(g, "form")
+    updateStatusBar(g, formInfoText)
+
+    return nil
+}
+
+func redrawForm(g *gocui.Gui) {
+    v, err := g.View("form")
+    if err != nil {
+        return
+    }
+    v.Clear()
+    for _, f := range activeForm.fields {
+        marker := "  "
+        if f.meta.Required {
+            marker = "* "
+        }
+        fmt.Fprintln(v, marker+f.meta.Name+": "+strings.Replace(f.value, "\n", " ", -1))
+    }
+}
+
+func editFormField(g *gocui.Gui, v *gocui.View) error {
+
+    _, cy := v.Cursor()
+    _, oy := v.Origin()
+    if cy+oy >= len(activeForm.fields) {
+        return nil
+    }
+    activeForm.editing = cy + oy
+    field := activeForm.fields[activeForm.editing]
+
+    maxX, maxY := g.Size()
+
+    // Arrays may hold several values, so they are typed instead
+    if len(field.meta.AllowedValues) > 0 && field.meta.Schema.Type != "array" {
+        height := len(field.meta.AllowedValues) + 1
+        if height > maxY-4 {
+            height = maxY - 4
+        }
+        if v, err := g.SetView("formValues", maxX/2-25, maxY/2-height/2-1, maxX/2+25, maxY/2+height/2+1); err != nil {
+            if err != gocui.ErrUnknownView {
+                return err
+            }
+            v.Editable = false
+            v.Highlight = true
+            v.Title = field.meta.Name
+            if !field.meta.Required {
+                fmt.Fprintln(v, "(none)")
+            }
+            for _, a := range field.meta.AllowedValues {
+                fmt.Fprintln(v, a.label())
+            }
+        }
+        setCurrentViewOnTop(g, "formValues")
+        updateStatusBar(g, "Choose: Enter  |  Back: Esc")
+        return nil
+    }
+
+    if v, err := g.SetView("formInput", maxX/2-30, maxY/2-2, maxX/2+30, maxY/2+2); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Editable = true
+        v.Wrap = true
+        v.Title = field.meta.Name + inputHint(field.meta)
+        fmt.Fprint(v, field.value)
+        if !strings.Contains(field.value, "\n") && len(field.value) < 58 {
+            v.SetCursor(len(field.value), 0)
+        }
+    }
+    g.Cursor = true
+    setCurrentViewOnTop(g, "formInput")
+
+    options := []string{}
+    for _, a := range field.meta.AllowedValues {
+        options = append(options, a.label())
+    }
+    if len(options) > 0 {
+        updateStatusBar(g, "Save: Ctrl-S  |  Open in editor: Ctrl-E  |  Back: Esc  |  Options: "+strings.Join(options, ", "))
+    } else {
+        updateStatusBar(g, "Save: Ctrl-S  |  Open in editor: Ctrl-E  |  Back: Esc")
+    }
+
+    return nil
+}
+
+// inputHint tells the user what kind of text we expect
+func inputHint(meta fieldMeta) string {
+    switch {
+    case meta.Schema.Type == "array":
+        return " (comma separated)"
+    case meta.Schema.Type == "date":
+        return " (YYYY-MM-DD)"
+    case meta.Schema.Type == "datetime":
+        return " (YYYY-MM-DD HH:MM)"
+    case meta.Schema.System == "worklog" || meta.Schema.Type == "timetracking":
+        return " (e.g. 1h 30m)"
+    }
+    return ""
+}
+
+func pickFormValue(g *gocui.Gui, v *gocui.View) error {
+
+    field := &activeForm.fields[activeForm.editing]
+
+    _, cy := v.Cursor()
+    _, oy := v.Origin()
+    choice := cy + oy
+    if !field.meta.Required {
+        choice--
+    }
+
+    if choice < 0 {
+        field.value = ""
+        field.valueID = ""
+    } else if choice < len(field.meta.AllowedValues) {
+        field.value = field.meta.AllowedValues[choice].label()
+        field.valueID = field.meta.AllowedValues[choice].ID
+    }
+
+    destroyView(g, v)
+    redrawForm(g)
+    return nil
+}
+
+func saveFormInput(g *gocui.Gui, v *gocui.View) error {
+    activeForm.fields[activeForm.editing].value = strings.TrimSpace(v.Buffer())
+    destroyView(g, v)
+    redrawForm(g)
+    return nil
+}
+
+func submitForm(g *gocui.Gui, v *gocui.View) error {
+    for _, f := range activeForm.fields {
+        if f.meta.Required && !f.meta.HasDefaultValue && f.value == "" {
+            updateStatusBar(g, f.meta.Name+" is required")
+            return nil
+        }
+    }
+    form := activeForm
+    destroyView(g, v)
+
+    // Bring the form back with the values, so user can fix them
+    if err := form.submit(g, form); err != nil {
+        openForm(g, form)
+        updateStatusBar(g, err.Error())
+    }
+    return nil
+}
+
+// fieldValue converts what user typed or chose to the value JIRA expects
+// for the type of the field.
+func fieldValue(f formField) (interface{}, error) {
+
+    if f.valueID != "" {
+        ref := map[string]string{"id": f.valueID}
+        if f.meta.Schema.Type == "array" {
+            return []map[string]string{ref}, nil
+        }
+        return ref, nil
+    }
+
+    switch f.meta.Schema.Type {
+    case "number":
+        number, err := strconv.ParseFloat(f.value, 64)
+        if err != nil {
+            return nil, errors.New(f.meta.Name + " should be a number")
+        }
+        return number, nil
+    case "date":
+        day, err := time.ParseInLocation("2006-01-02", f.value, time.Local)
+        if err != nil {
+            return nil, errors.New(f.meta.Name + " should look like YYYY-MM-DD")
+        }
+        return day.Format("2006-01-02"), nil
+    case "datetime":
+        // Typed like the start of a worklog, JIRA wants the zone too
+        moment, err := time.ParseInLocation(startedLayout, f.value, time.Local)
+        if err != nil {
+            return nil, errors.New(f.meta.Name + " should look like YYYY-MM-DD HH:MM")
+        }
+        return moment.Format(jiraDateTimeLayout), nil
+    case "user":
+        return map[string]string{"name": f.value}, nil
+    case "option":
+        return map[string]string{"value": f.value}, nil
+    case "priority":
+        return map[string]string{"name": f.value}, nil
+    case "array":
+        items := []string{}
+        for _, s := range strings.Split(f.value, ",") {
+            if s = strings.TrimSpace(s); s != "" {
+                items = append(items, s)
+            }
+        }
+        if f.meta.Schema.Items == "string" {
+            return items, nil
+        }
+        refs := []map[string]string{}
+        for _, s := range items {
+            refs = append(refs, map[string]string{"name": s})
+        }
+        return refs, nil
+    }
+    return f.value, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+    "bytes"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "os/exec"
+    "regexp"
+    "strings"
+    "text/template"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+var defaultBranchTemplate = "{{.Key}}-{{slug .Summary}}"
+
+// gitBranches are the local branches by the issue keys in their names,
+// gitCommits the keys mentioned in the recent commits. They are empty
+// outside a git repository.
+var (
+    gitBranches = map[string][]string{}
+    gitCommits  = map[string]bool{}
+)
+
+// slugPattern finds what can't be in a branch name
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// git runs git in the directory jb was started in
+func git(args ...string) (string, error) {
+    var stderr bytes.Buffer
+    cmd := exec.Command("git", args...)
+    cmd.Stderr = &stderr
+    output, err := cmd.Output()
+    if err != nil {
+        if message := strings.TrimSpace(stderr.String()); message != "" {
+            return "", errors.New(message)
+        }
+        return "", err
+    }
+    return strings.TrimSpace(string(output)), nil
+}
+
+func inGitRepository() bool {
+    _, err := git("rev-parse", "--show-toplevel")
+    return err == nil
+}
+
+// slug makes the text usable in a branch name
+func slug(text string) string {
+    text = strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(text), "-"), "-")
+    if len(text) > 50 {
+        text = strings.TrimRight(text[:50], "-")
+    }
+    return text
+}
+
+// branchName fills the branch template with the issue
+func branchName(issue jira.Issue) (string, error) {
+
+    conf, err := readConfig()
+    if err != nil {
+        return "", err
+    }
+    text := conf.branchTemplate
+    if text == "" {
+        text = defaultBranchTemplate
+    }
+    branchTemplate, err := template.New("branch").Funcs(template.FuncMap{"slug": slug}).Parse(text)
+    if err != nil {
+        return "", &jbError{kind: errConfig, err: err}
+    }
+
+    var name bytes.Buffer
+    data := map[string]string{"Key": issue.Key, "Summary": issue.Fields.Summary, "Type": issue.Fields.Type.Name}
+    if err := branchTemplate.Execute(&name, data); err != nil {
+        return "", &jbError{kind: errConfig, err: err}
+    }
+    return name.String(), nil
+}
+
+// loadGitRefs finds the branches and the recent commits which mention
+// the issues. Keys are matched in upper case only, like in the preview,
+// so release-2 is not taken for an issue.
+func loadGitRefs() {
+
+    gitBranches = map[string][]string{}
+    gitCommits = map[string]bool{}
+
+    branches, err := git("for-each-ref", "--format=%(refname:short)", "refs/heads")
+    if err != nil {
+        return
+    }
+    for _, branch := range strings.Split(branches, "\n") {
+        for _, key := range issueKeyPattern.FindAllString(branch, -1) {
+            gitBranches[key] = append(gitBranches[key], branch)
+        }
+    }
+
+    messages, err := git("log", "--all", "--format=%B", "-n", "1000")
+    if err != nil {
+        return
+    }
+    for _, key := range issueKeyPattern.FindAllString(messages, -1) {
+        gitCommits[key] = true
+    }
+}
+
+// gitMarker tells on the card if there is a branch or a commit for the
+// issue.
+func gitMarker(issueKey string) string {
+    if len(gitBranches[issueKey]) > 0 {
+        return "\x1b[0;36m[branch]" + resetColor + " "
+    }
+    if gitCommits[issueKey] {
+        return "\x1b[0;36m[commits]" + resetColor + " "
+    }
+    return ""
+}
+
+// gitMenuItems are the branch actions, inside a repository only
+func gitMenuItems(issue jira.Issue) []string {
+    if !inGitRepository() {
+        return []string{}
+    }
+    items := []string{"Create branch for issue"}
+    if len(gitBranches[issue.Key]) > 0 {
+        items = append(items, "Checkout branch for issue")
+    }
+    return items
+}
+
+func runGitAction(g *gocui.Gui, issue jira.Issue, action string) error {
+
+    if action == "Checkout branch for issue" {
+        branches := gitBranches[issue.Key]
+        if len(branches) == 1 {
+            return checkoutBranch(g, issue, branches[0], false)
+        }
+        return openPicker(g, "Checkout branch", branches, func(g *gocui.Gui, branch string) error {
+            return checkoutBranch(g, issue, branch, false)
+        })
+    }
+
+    name, err := branchName(issue)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    return openPrompt(g, "Create branch", name, func(g *gocui.Gui, branch string) error {
+        if branch == "" {
+            return nil
+        }
+        return checkoutBranch(g, issue, branch, true)
+    })
+}
+
+func checkoutBranch(g *gocui.Gui, issue jira.Issue, branch string, create bool) error {
+
+    args := []string{"checkout", branch}
+    if create {
+        args = []string{"checkout", "-b", branch}
+    }
+    if _, err := git(args...); err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    loadGitRefs()
+    redrawCard(g, issue)
+    updateStatusBar(g, "Switched to branch "+branch)
+    return nil
+}
+
+// currentCommand prints the issue of the checked out branch, for shell
+// prompts.
+func currentCommand(args []string) error {
+
+    flags := flag.NewFlagSet("current", flag.ContinueOnError)
+    withSummary := flags.Bool("summary", false, "")
+    if _, err := parseCommand(flags, args, 0); err != nil {
+        return err
+    }
+
+    branch, err := git("rev-parse", "--abbrev-ref", "HEAD")
+    if err != nil {
+        return err
+    }
+    key := issueKeyPattern.FindString(branch)
+    if key == "" {
+        return errors.New("branch " + branch + " is not for an issue")
+    }
+
+    if !*withSummary {
+        fmt.Println(key)
+        return nil
+    }
+    issue, err := fetchIssue(key)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        fmt.Println(key)
+        return nil
+    }
+    fmt.Println(key + " " + issue.Fields.Summary)
+    return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/jroimartin/gocui"
+)
+
+// helpReturn is where the focus goes back to when the help is closed,
+// with the status line and the cursor as they were.
+var helpReturn = struct {
+    view   string
+    status string
+    cursor bool
+}{}
+
+// typingViews take every character, only the keys which aren't
+// characters are bound there.
+var typingViews = []string{"msgBox", "formInput", "prompt"}
+
+// fixedHelp lists the keys of the typing views, they are not in the
+// keymap.
+var fixedHelp = map[string][][2]string{
+    "msgBox":    {{"Ctrl-S", "Send the comment"}, {"Ctrl-E", "Write it in the editor"}, {"Esc", "Close"}},
+    "formInput": {{"Ctrl-S", "Save the field"}, {"Ctrl-E", "Write it in the editor"}, {"Esc", "Cancel"}},
+    "prompt":    {{"Enter", "Done"}, {"Tab", "Complete"}, {"Esc", "Cancel"}},
+}
+
+// viewTitles name the views in the title of the help
+var viewTitles = map[string]string{
+    cardView:     "Board",
+    "statusLine": "Board",
+    "menu":       "Actions menu",
+    "picker":     "Choice",
+    "form":       "Form",
+    "formValues": "Form values",
+    "formInput":  "Editor",
+    "msgBox":     "Comment",
+    "prompt":     "Prompt",
+    "previewBox": "Preview",
+    "backlog":    "Backlog",
+    "errorBox":   "Error",
+    "help":       "Help",
+}
+
+// keymapView is the name of the view in the keymap, cards are all the
+// same there.
+func keymapView(name string) string {
+    if _, ok := viewTitles[name]; ok {
+        return name
+    }
+    return cardView
+}
+
+// helpLines lists the keys working in the view, the ones working
+// everywhere last.
+func helpLines(view string) []string {
+
+    lines := []string{}
+    for _, keys := range fixedHelp[view] {
+        lines = append(lines, fmt.Sprintf("%-16s %s", keys[0], keys[1]))
+    }
+    for _, global := range []bool{false, true} {
+        for _, action := range keyActions {
+            for _, actionView := range action.views {
+                if (global && actionView != "") || (!global && actionView != view) {
+                    continue
+                }
+                keys := boundKeys(action, view)
+                if len(keys) == 0 {
+                    continue
+                }
+                lines = append(lines, fmt.Sprintf("%-16s %s", strings.Join(keys, ", "), action.help))
+            }
+        }
+    }
+    return lines
+}
+
+// boundKeys are the keys of the action which work in the view
+func boundKeys(action keyAction, view string) []string {
+    keys := []string{}
+    for _, keyName := range action.keys {
+        key, err := parseKey(keyName)
+        if err != nil {
+            continue
+        }
+        if _, isRune := key.key.(rune); isRune && key.mod == gocui.ModNone && indexOf(view, typingViews) != -1 {
+            continue
+        }
+        keys = append(keys, keyName)
+    }
+    return keys
+}
+
+func toggleHelp(g *gocui.Gui, v *gocui.View) error {
+
+    if _, err := g.View("help"); err == nil {
+        return closeHelp(g, v)
+    }
+    if v == nil {
+        return nil
+    }
+
+    helpReturn.view = v.Name()
+    helpReturn.cursor = g.Cursor
+    helpReturn.status = ""
+    if statusView, err := g.View("statusLine"); err == nil {
+        helpReturn.status = strings.TrimSuffix(statusView.Buffer(), "\n")
+    }
+
+    view := keymapView(v.Name())
+    lines := helpLines(view)
+
+    maxX, maxY := g.Size()
+    height := len(lines) + 1
+    if height > maxY-4 {
+        height = maxY - 4
+    }
+    helpView, err := g.SetView("help", maxX/2-35, maxY/2-height/2-1, maxX/2+35, maxY/2+height/2+1)
+    if err != nil && err != gocui.ErrUnknownView {
+        return err
+    }
+    helpView.Clear()
+    helpView.Editable = false
+    helpView.Highlight = true
+    helpView.Title = "Keys: " + viewTitles[view]
+    for _, line := range lines {
+        fmt.Fprintln(helpView, line)
+    }
+
+    g.Cursor = false
+    setCurrentViewOnTop(g, "help")
+    updateStatusBar(g, "Scroll: "+keyHint("up")+" "+keyHint("down")+"  |  Close: "+keyHint("help")+" or "+keyHint("close"))
+    return nil
+}
+
+func closeHelp(g *gocui.Gui, v *gocui.View) error {
+
+    g.DeleteView("help")
+    g.Cursor = helpReturn.cursor
+    if _, err := g.View(helpReturn.view); err == nil {
+        setCurrentViewOnTop(g, helpReturn.view)
+    } else {
+        activateFirstIssue(g)
+    }
+    if statusView, err := g.View("statusLine"); err == nil {
+        statusView.Clear()
+        fmt.Fprint(statusView, helpReturn.status)
+    }
+    return nil
+}
@@ -8,8 +8,8 @@ import (
     "sort"
     "strconv"
     "strings"
+    "time"
     "flag"
-    "hash/fnv"
 
     jira "github.com/andygrunwald/go-jira"
     "github.com/jroimartin/gocui"
@@ -34,7 +34,7 @@ type activeBox struct {
     indexno          int
     issuetitle       string
     issueurl         string
-    availableActions map[string]string
+    availableActions map[string]jira.Transition
 }
 
 type configItem struct {
@@ -43,16 +43,26 @@ type configItem struct {
     password       string
     query          string
     browserCommand string
+    defaultProject string
+    editorCommand  string
+    epicLinkField  string
+    boardID        int
+    downloadDir    string
+    keymap         map[string]interface{}
+    theme          map[string]interface{}
+    mouse          bool
+    branchTemplate string
 }
 
 var (
     kanbanlist    = []jira.Issue{}
     kanbanMatrix  = []column{}
     active        = &activeBox{}
+    menuIssue     = jira.Issue{}
     jiraClient    = &jira.Client{}
     configColumns = []string{}
     moveCounter   = 0
-    infoText      = "Navigation: Arrow keys  |  Actions Menu: Spacebar  |  Exit: Ctrl-C | Reload: F5"
+    infoText      = "Navigation: Arrow keys  |  Mark: x, column: X, all: Ctrl-A  |  Move card: < >  |  New issue: n  |  Edit: e  |  Timer: t  |  Epics: E  |  Sprints: S  |  Backlog: B  |  Actions Menu: Spacebar  |  Exit: Ctrl-C | Reload: F5"
 )
 
 var log = logrus.New()
@@ -61,62 +71,78 @@ var log = logrus.New()
 // I hope your terminal is clever enough
 var resetColor = "\x1b[0m"
 
+// colorHash picks a color of the theme for the input, the same one each
+// time.
 func colorHash(input string) string {
-    var colorPalette = []string{
-        "\x1b[0;31m", //red
-        "\x1b[0;32m", //green
-        "\x1b[0;33m", //brown
-        "\x1b[0;34m", //blue
-        "\x1b[0;35m", //purple
-        "\x1b[0;36m", //cyan
-        "\x1b[0;37m", //gray
-    }
-
-    h := fnv.New32a()
-    h.Write([]byte(input))
-
-    generatedInt := int(h.Sum32())
-
-    decidedColor := colorPalette[generatedInt%(len(colorPalette)-1)]
-
-    return decidedColor
+    return hashColor(input).ansi()
 }
 
 // jiraAction function applies the specified action for the key, which
-// is the issue.
+// is the issue. If the transition has a screen, it is asked first.
 func jiraAction(g *gocui.Gui, issue *jira.Issue, action string) {
 
-    if !jiraClient.Authentication.Authenticated() {
-        jiraClient = getJiraAuth()
-    }
-
     updateStatusBar(g, "Action: "+action+". Issue: "+issue.ID)
 
     realAction := strings.Split(action, "> ")[1]
 
-    actionID := active.availableActions[realAction]
+    transition := active.availableActions[realAction]
+
+    menuView, _ := g.View("menu")
+    destroyView(g, menuView)
+
+    if len(transition.Fields) > 0 {
+        issueID := issue.ID
+        issueKey := issue.Key
+        openTransitionForm(g, issueID, issue.Key, transition, func(g *gocui.Gui, payload interface{}) error {
+            if res, err := jiraClient.Issue.DoTransitionWithPayload(issueID, payload); err != nil {
+                if err = newJiraError(res, err); isOffline(err) {
+                    return queueTransition(g, issueID, issueKey, transition.To.Name, payload)
+                }
+                return err
+            }
+            return refreshBoard(g, nil)
+        })
+        return
+    }
 
-    res, err := jiraClient.Issue.DoTransition(issue.ID, actionID)
+    var res *jira.Response
+    err := ensureAuthenticated()
+    if err == nil {
+        res, err = jiraClient.Issue.DoTransition(issue.ID, transition.ID)
+        err = newJiraError(res, err)
+    }
     if err != nil {
-        updateStatusBar(g, err.Error())
+        if isOffline(err) {
+            queueTransition(g, issue.ID, issue.Key, transition.To.Name, jira.CreateTransitionPayload{
+                Transition: jira.TransitionPayload{ID: transition.ID},
+            })
+            return
+        }
+        showError(g, err)
+        return
     }
 
-    menuView, _ := g.View("menu")
-    destroyView(g, menuView)
+    refreshBoard(g, nil)
 
     updateStatusBar(g, res.Status)
 
 }
 
 // getJiraAuth function creates the initial JIRA authentication cookie
-// It uses the pre-read variables jiraUsername and jiraPassword
-func getJiraAuth() *jira.Client {
+// It uses the username and password of the given config
+func getJiraAuth(config configItem) (*jira.Client, error) {
 
-    config := readConfig()
-
-    jiraClient, err := jira.NewClient(nil, config.instanceURL)
+    httpClient, transport := newJiraHTTPClient()
+    jiraClient, err := jira.NewClient(httpClient, config.instanceURL)
     if err != nil {
-        panic(err)
+        return nil, &jbError{kind: errConfig, err: err}
+    }
+    transport.reauth = func() error {
+        ok, err := jiraClient.Authentication.AcquireSessionCookie(config.username, config.password)
+        if err == nil && !ok {
+            err = errors.New("JIRA didn't accept the username and password")
+        }
+        return err
     }
 
     res, err := jiraClient.Authentication.AcquireSessionCookie(
@@ -124,30 +150,52 @@ func getJiraAuth() *jira.Client {
         config.password,
     )
     if err != nil || res == false {
-        fmt.Printf("Result: %v\n", res)
-        panic(err)
+        // go-jira hides the response here, only the message tells
+        // if the server answered us at all.
+        if err != nil && !strings.Contains(err.Error(), "Status code") {
+            return nil, &jbError{kind: errNetwork, err: err}
+        }
+        return nil, &jbError{kind: errAuth, err: errors.New("JIRA didn't accept the username and password")}
     }
 
-    return jiraClient
+    return jiraClient, nil
 
 }
 
-// executeQuery function executes the JQL query specified in config file
-func executeQuery(conf configItem) []jira.Issue {
+// ensureAuthenticated logs in, if we don't have a session yet. It replaces
+// jiraClient, so only the UI goroutine (or a command) may call it;
+// background work is started after it.
+func ensureAuthenticated() error {
 
-    if !jiraClient.Authentication.Authenticated() {
-        jiraClient = getJiraAuth()
+    if jiraClient.Authentication.Authenticated() {
+        return nil
     }
 
-    config := readConfig()
+    config, err := readConfig()
+    if err != nil {
+        return err
+    }
+    client, err := getJiraAuth(config)
+    if err != nil {
+        return err
+    }
+    jiraClient = client
+    return nil
+}
 
-    issuelist, _, err := jiraClient.Issue.Search(config.query, nil)
+// executeQuery function executes the JQL query specified in config file
+func executeQuery(conf configItem) ([]jira.Issue, error) {
+
+    if err := ensureAuthenticated(); err != nil {
+        return nil, err
+    }
+
+    issuelist, res, err := jiraClient.Issue.Search(boardQuery(conf), nil)
     if err != nil {
-        fmt.Printf("Result: %v\n", err)
-        panic(err)
+        return nil, newJiraError(res, err)
     }
 
-    return issuelist
+    return issuelist, nil
 }
 
 func activateFirstIssue(g *gocui.Gui) {
@@ -160,6 +208,29 @@ func activateFirstIssue(g *gocui.Gui) {
             return
         }
     }
+    // Nothing on the board, the status line keeps the keys working
+    g.SetCurrentView("statusLine")
+}
+
+// focusCard makes the given issue the active one, scrolling its column
+// if the card is below the screen.
+func focusCard(g *gocui.Gui, key string) {
+    for i := range kanbanMatrix {
+        index := indexOfView(key, kanbanMatrix[i].members)
+        if index < 0 {
+            continue
+        }
+        moveIssues(g, 1000, true)
+        active.columnname = kanbanMatrix[i].view.Title
+        active.issuetitle = key
+        active.indexno = index
+        for m := 0; m < index-4; m++ {
+            moveIssues(g, -6, false)
+        }
+        setCurrentViewOnTop(g, key)
+        g.SetViewOnTop("statusLine")
+        return
+    }
 }
 
 func setCurrentViewOnTop(g *gocui.Gui, name string) (*gocui.View, error) {
@@ -200,11 +271,12 @@ func getMenuSelection(g *gocui.Gui, v *gocui.View) error {
         line = ""
     }
 
-    // Get active column
-    currentColumn, err := getColumn(active.columnname)
-    if err != nil {
-        log.Fatal("Couldn't find active column, something is very wrong.")
-    }
+    return runMenuAction(g, menuIssue, line)
+}
+
+// runMenuAction runs a line of the actions menu on the issue, the menu
+// itself may not be open when it is run from a shortcut.
+func runMenuAction(g *gocui.Gui, issue jira.Issue, line string) error {
 
     switch line {
     case "Comment on issue":
@@ -214,77 +286,86 @@ func getMenuSelection(g *gocui.Gui, v *gocui.View) error {
                 return err
             }
             v.Editable = true
-            v.Title = "Comment on issue " + active.issuetitle
+            v.Wrap = true
+            v.Title = "Comment on issue " + issue.Key
             setCurrentViewOnTop(g, "msgBox")
         }
+        // Bindings of the previous comment box would send it twice
+        g.DeleteKeybindings("msgBox")
+        if err := bindKeys(g, "msgBox", "msgBox"); err != nil {
+            return err
+        }
         if err := g.SetKeybinding("msgBox", gocui.KeyEsc, gocui.ModNone, destroyView); err != nil {
-            log.Panicln(err)
+            return err
         }
         if err := g.SetKeybinding("msgBox", gocui.KeyCtrlS, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
-            updateStatusBar(g, "Sorry, not implemented yet!")
+            body := v.Buffer()
             destroyView(g, v)
+            sendComment(g, issue, body)
             return nil
         }); err != nil {
-            log.Panicln(err)
-        }
-        updateStatusBar(g, "Send: Ctrl-S (not implemented yet)  |  Close: Esc")
-    case "Preview issue":
-        maxX, maxY := g.Size()
-        if v, err := g.SetView("previewBox", 5, 3, maxX-5, maxY-3); err != nil {
-            if err != gocui.ErrUnknownView {
-                return err
-            }
-            v.Title = "Details of " + active.issuetitle
-            v.Autoscroll = false
-            v.Wrap = true
-            v.Editable = true
-            issue := currentColumn.members[active.indexno].issue
-            lineSlice := strings.SplitN(
-                issue.Fields.Description,
-                "\r\n",
-                -1,
-            )
-            fmt.Fprintln(v, "Reporter: " + issue.Fields.Reporter.DisplayName)
-            if (issue.Fields.Assignee != nil) {
-                fmt.Fprintln(v, "Assignee: " + issue.Fields.Assignee.DisplayName)
-            }
-            if (issue.Fields.Labels != nil) {
-                if len(issue.Fields.Labels) > 0 {
-                    fmt.Fprintln( v, "Labels: " + strings.Join(issue.Fields.Labels, ","))
-                }
-            }
-            if (issue.Fields.Priority != nil) {
-                fmt.Fprintln(v, "Priority: "+issue.Fields.Priority.Name)
-            }
-            fmt.Fprint(v, "Description:\n\n")
-            for i := range lineSlice {
-                fmt.Fprintln(v, lineSlice[i])
-            }
-
-            setCurrentViewOnTop(g, "previewBox")
+            return err
         }
-        if err := g.SetKeybinding("previewBox", gocui.KeyEsc, gocui.ModNone, destroyView); err != nil {
-            log.Panicln(err)
+        if err := g.SetKeybinding("msgBox", gocui.KeyCtrlE, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+            return commentInEditor(g, v, issue.Key, func(g *gocui.Gui, body string) {
+                sendComment(g, issue, body)
+            })
+        }); err != nil {
+            return err
         }
-        updateStatusBar(g, "Close: Esc")
+        updateStatusBar(g, "Send: Ctrl-S  |  Open in editor: Ctrl-E  |  Close: Esc")
+    case "Preview issue":
+        previewHistory = []jira.Issue{}
+        return showPreview(g, issue)
+    case "Edit issue":
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        return openEditForm(g, issue)
+    case "Log work":
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        return logWorkHandler(g, issue)
+    case "Move to sprint":
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        return moveToSprintHandler(g, issue)
+    case "Move to backlog":
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        return moveToBacklog(g, issue)
+    case "Watch issue", "Stop watching", "Vote for issue", "Remove vote", "Flag issue", "Remove flag":
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        return runTriageAction(g, issue, line)
+    case "Start timer", "Stop timer":
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        return toggleTimer(g, issue)
+    case "Create branch for issue", "Checkout branch for issue":
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        return runGitAction(g, issue, line)
     case "Open in browser":
-        conf := readConfig()
+        menuView, _ := g.View("menu")
+        destroyView(g, menuView)
+        conf, err := readConfig()
+        if err != nil {
+            showError(g, err)
+            return nil
+        }
         issueURL := ""
-        issueURL = conf.instanceURL + "/browse/" + currentColumn.members[active.indexno].issue.Key
+        issueURL = conf.instanceURL + "/browse/" + issue.Key
         cmd := exec.Command(conf.browserCommand, issueURL)
         err = cmd.Start()
         if err != nil {
-            log.Fatal(err)
+            showError(g, &jbError{kind: errConfig, err: err})
         }
-        menuView, _ := g.View("menu")
-        destroyView(g, menuView)
     case "":
         log.Debug("User chose nothing, closing the menu, that'll teach the user..")
         menuView, _ := g.View("menu")
         destroyView(g, menuView)
     default:
-        jiraAction(g, &currentColumn.members[active.indexno].issue, line)
-        refreshBoard(g, v)
+        jiraAction(g, &issue, line)
     }
 
     return nil
@@ -294,12 +375,21 @@ func destroyView(g *gocui.Gui, v *gocui.View) error {
     g.Cursor = false
     if v != nil {
         g.DeleteView(v.Name())
-        if _, err := g.View("menu"); err != nil {
-            setCurrentViewOnTop(g, active.issuetitle)
-            updateStatusBar(g, infoText)
-        } else {
+        if _, err := g.View("form"); err == nil {
+            updateStatusBar(g, formInfoText)
+            setCurrentViewOnTop(g, "form")
+        } else if _, err := g.View("previewBox"); err == nil {
+            updateStatusBar(g, previewInfoText)
+            setCurrentViewOnTop(g, "previewBox")
+        } else if _, err := g.View("menu"); err == nil {
             updateStatusBar(g, "")
             setCurrentViewOnTop(g, "menu")
+        } else if _, err := g.View("backlog"); err == nil {
+            updateStatusBar(g, backlogInfoText)
+            setCurrentViewOnTop(g, "backlog")
+        } else {
+            setCurrentViewOnTop(g, active.issuetitle)
+            updateStatusBar(g, infoText)
         }
     }
     return nil
@@ -307,13 +397,19 @@ func destroyView(g *gocui.Gui, v *gocui.View) error {
 
 func openMenu(g *gocui.Gui, v *gocui.View) error {
 
+    // Marked cards get the actions which can run on all of them
+    if len(markedIssues()) > 0 {
+        return openBulkMenu(g)
+    }
+
     menuCoord := [4]int{}
 
     maxX, maxY := g.Size()
     activeIssue := jira.Issue{}
     currentColumn, err := getColumn(active.columnname)
     if err != nil {
-        log.Fatal("Couldn't find active column, something is very wrong.")
+        showError(g, errors.New("Couldn't find active column, something is very wrong."))
+        return nil
     }
 
     xzero, _, _, yone, _ := g.ViewPosition(currentColumn.members[active.indexno].view.Title)
@@ -330,14 +426,24 @@ func openMenu(g *gocui.Gui, v *gocui.View) error {
     }
     activeIssue = currentColumn.members[active.indexno].issue
 
-    actionMap := map[string]string{}
+    return openIssueMenu(g, activeIssue, menuCoord)
+}
+
+// openIssueMenu opens the actions menu of the issue at the given place,
+// the actions run on menuIssue.
+func openIssueMenu(g *gocui.Gui, activeIssue jira.Issue, menuCoord [4]int) error {
+
+    menuIssue = activeIssue
+
+    actionMap := map[string]jira.Transition{}
     // Let's first check if the issue belongs to us
-    availActions, _, err := jiraClient.Issue.GetTransitions(activeIssue.ID)
+    availActions, err := getTransitions(activeIssue.ID)
     if err != nil {
-        return err
+        showError(g, err)
+        return nil
     }
     for k := range availActions {
-        actionMap[availActions[k].To.Name] = availActions[k].ID
+        actionMap[availActions[k].To.Name] = availActions[k]
     }
 
     active.availableActions = actionMap
@@ -361,18 +467,27 @@ func openMenu(g *gocui.Gui, v *gocui.View) error {
         }
         fmt.Fprintln(v, "Open in browser")
         fmt.Fprintln(v, "Preview issue")
+        fmt.Fprintln(v, "Edit issue")
         fmt.Fprintln(v, "Comment on issue")
+        fmt.Fprintln(v, "Log work")
+        for _, item := range triageMenuItems(activeIssue) {
+            fmt.Fprintln(v, item)
+        }
+        if _, ok := timers[activeIssue.Key]; ok {
+            fmt.Fprintln(v, "Stop timer")
+        } else {
+            fmt.Fprintln(v, "Start timer")
+        }
+        for _, item := range gitMenuItems(activeIssue) {
+            fmt.Fprintln(v, item)
+        }
+        if len(sprints) > 0 {
+            fmt.Fprintln(v, "Move to sprint")
+            fmt.Fprintln(v, "Move to backlog")
+        }
     }
 
-    if err := g.SetKeybinding("menu", gocui.KeyEsc, gocui.ModNone, destroyView); err != nil {
-        log.Panicln(err)
-    }
-
-    if err := g.SetKeybinding("menu", gocui.KeySpace, gocui.ModNone, destroyView); err != nil {
-        log.Panicln(err)
-    }
-
-    updateStatusBar(g, "Run action: Enter  |  Close menu: Esc or Spacebar")
+    updateStatusBar(g, "Run action: "+keyHint("select")+"  |  Close menu: "+keyHint("close")+" or "+keyHint("menu"))
     setCurrentViewOnTop(g, "menu")
 
     return nil
@@ -382,6 +497,65 @@ func refreshBoard(g *gocui.Gui, v *gocui.View) error {
 
     updateStatusBar(g, "Refreshing board...")
 
+    conf, err := readConfig()
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    // Changes made while offline go first, so the query sees them
+    if hasPendingOps() && ensureAuthenticated() == nil {
+        syncPendingOps(g, jiraClient)
+    }
+    devCache = map[string]devStatus{}
+
+    // In scrum mode the query depends on the sprint
+    if conf.boardID != 0 {
+        if err := loadSprints(conf); err != nil && !isOffline(err) {
+            showError(g, err)
+            return nil
+        }
+    }
+
+    // Query first, so a failing query keeps the board we already have
+    issues, err := executeQuery(conf)
+    if err != nil {
+        if isOffline(err) && len(cache.Issues) > 0 && cache.Query == boardQuery(conf) {
+            staleSince = cache.SavedAt
+            updateStatusBar(g, "Offline, showing the board as it was loaded last time")
+            return nil
+        }
+        showError(g, err)
+        return nil
+    }
+
+    resolveCustomFields(conf)
+    resolveEpics(issues)
+    if err := placeIssues(g, issues); err != nil {
+        return err
+    }
+
+    staleSince = time.Time{}
+    cache.SavedAt = time.Now()
+    cache.Query = boardQuery(conf)
+    saveBoardCache()
+
+    // The backlog is loaded again too, and stays in front
+    if _, err := g.View("backlog"); err == nil {
+        return loadBacklog(g)
+    }
+
+    if conf.boardID != 0 && cache.Sprint.ID == 0 && len(sprints) == 0 && staleSince.IsZero() {
+        updateStatusBar(g, "Board "+strconv.Itoa(conf.boardID)+" has no sprints, showing the whole query  |  "+infoText)
+        return nil
+    }
+    updateStatusBar(g, infoText)
+
+    return nil
+}
+
+// clearBoard removes all the cards
+func clearBoard(g *gocui.Gui) {
     for i := range kanbanMatrix {
         for m := range kanbanMatrix[i].members {
             g.DeleteKeybindings(kanbanMatrix[i].members[m].view.Title)
@@ -391,22 +565,56 @@ func refreshBoard(g *gocui.Gui, v *gocui.View) error {
     for i := range kanbanMatrix {
         kanbanMatrix[i].members = kanbanMatrix[i].members[:0]
     }
+}
 
-    conf := readConfig()
+// placeIssues puts the issues on an empty board. The ones the epic
+// filter hides are kept aside, they are still part of the board.
+func placeIssues(g *gocui.Gui, issues []jira.Issue) error {
 
-    for _, issue := range executeQuery(conf) {
-        createIssue(g, issue)
+    clearBoard(g)
+    hiddenIssues = []jira.Issue{}
+    loadGitRefs()
+    if flaggedFirst {
+        issues = sortFlaggedFirst(issues)
     }
 
-    g.SetViewOnTop("statusLine")
-
-    activateFirstIssue(g)
+    // The epic might be gone from the board, don't leave it empty
+    if epicFilter != "" {
+        found := false
+        for _, issue := range issues {
+            found = found || inEpicFilter(issue)
+        }
+        if !found {
+            epicFilter = ""
+        }
+    }
 
-    updateStatusBar(g, infoText)
+    for _, issue := range issues {
+        if !inEpicFilter(issue) {
+            hiddenIssues = append(hiddenIssues, issue)
+            continue
+        }
+        if err := createIssue(g, issue); err != nil {
+            return err
+        }
+    }
 
+    g.SetViewOnTop("statusLine")
+    activateFirstIssue(g)
     return nil
 }
 
+// boardIssues lists every issue of the board, hidden ones included
+func boardIssues() []jira.Issue {
+    issues := []jira.Issue{}
+    for i := range kanbanMatrix {
+        for m := range kanbanMatrix[i].members {
+            issues = append(issues, kanbanMatrix[i].members[m].issue)
+        }
+    }
+    return append(issues, hiddenIssues...)
+}
+
 func giveNextIssueCoord(g *gocui.Gui, col column) ([4]int, error) {
 
     issueCoord := [4]int{}
@@ -431,6 +639,56 @@ func giveNextIssueCoord(g *gocui.Gui, col column) ([4]int, error) {
 
 }
 
+// cardText is what we write into the box of the issue on the board
+func cardText(issue jira.Issue) string {
+    componentList := ""
+    for i, v := range issue.Fields.Components {
+        if i == 0 {
+            componentList = componentList + "["
+        }
+        componentList = componentList + componentColor(v.Name) + v.Name + resetColor + " "
+        if i == len(issue.Fields.Components)-1 {
+            componentList = strings.TrimRight(componentList, " ") + "]\n"
+        }
+    }
+    blocked := ""
+    if isBlocked(issue) {
+        blocked = "\x1b[0;31m[blocked]" + resetColor + " "
+    }
+    summary := issue.Fields.Summary
+    epic := ""
+    if epicKey := epicOf(issue); epicKey != "" {
+        epic = "<" + epicName(epicKey) + ">\n"
+        if colorByEpic {
+            epic = colorHash(epicKey) + epic + resetColor
+            summary = colorHash(epicKey) + summary + resetColor
+        }
+    }
+    mark := ""
+    if markedCards[issue.Key] {
+        mark = "[x] "
+    }
+    if isFlagged(issue) {
+        blocked = "\x1b[0;33m[flag]" + resetColor + " " + blocked
+    }
+    return componentList + epic + mark + blocked + gitMarker(issue.Key) + summary + timerMarker(issue.Key)
+}
+
+// redrawCard replaces the issue of an existing card and writes it again
+func redrawCard(g *gocui.Gui, issue jira.Issue) {
+    for i := range kanbanMatrix {
+        index := indexOfView(issue.Key, kanbanMatrix[i].members)
+        if index < 0 {
+            continue
+        }
+        box := &kanbanMatrix[i].members[index]
+        box.issue = issue
+        box.view.Clear()
+        fmt.Fprintln(box.view, cardText(issue))
+        return
+    }
+}
+
 func createIssue(g *gocui.Gui, issue jira.Issue) error {
 
     correctColumn := column{}
@@ -461,33 +719,14 @@ func createIssue(g *gocui.Gui, issue jira.Issue) error {
             return err
         }
         v.Wrap = true
-        componentList := ""
-        for i, v := range issue.Fields.Components {
-            if i == 0 {
-                componentList = componentList + "["
-            }
-            componentList = componentList + colorHash(v.Name) + v.Name + resetColor + " "
-            if i == len(issue.Fields.Components)-1 {
-                componentList = strings.TrimRight(componentList, " ") + "]\n"
-            }
-        }
         v.Title = issue.Key
-        fmt.Fprintln(v, componentList + issue.Fields.Summary)
+        fmt.Fprintln(v, cardText(issue))
         registerIssue(issueBox{view: v, issue: issue})
-        if err := g.SetKeybinding(issue.Key, gocui.KeyArrowDown, gocui.ModNone, downHandler); err != nil {
-            log.Panicln(err)
-        }
-        if err := g.SetKeybinding(issue.Key, gocui.KeyArrowUp, gocui.ModNone, upHandler); err != nil {
-            log.Panicln(err)
-        }
-        if err := g.SetKeybinding(issue.Key, gocui.KeyArrowRight, gocui.ModNone, rightHandler); err != nil {
-            log.Panicln(err)
-        }
-        if err := g.SetKeybinding(issue.Key, gocui.KeyArrowLeft, gocui.ModNone, leftHandler); err != nil {
-            log.Panicln(err)
+        if err := bindKeys(g, issue.Key, cardView); err != nil {
+            return err
         }
-        if err := g.SetKeybinding(issue.Key, gocui.KeySpace, gocui.ModNone, openMenu); err != nil {
-            log.Panicln(err)
+        if err := bindCardMouse(g, issue.Key, clickCard, rightClickCard); err != nil {
+            return err
         }
     }
 
@@ -498,7 +737,8 @@ func moveIssues(g *gocui.Gui, dy int, reset bool) error {
     // Get active column
     currentColumn, err := getColumn(active.columnname)
     if err != nil {
-        log.Fatal("Exiting!")
+        log.Warn("Couldn't find active column: " + active.columnname)
+        return nil
     }
 
     if reset {
@@ -681,7 +921,8 @@ func upDownView(g *gocui.Gui, v *gocui.View, direction string) error {
     // Get active column
     currentColumn, err := getColumn(active.columnname)
     if err != nil {
-        log.Fatal("Exiting!")
+        log.Warn("Couldn't find active column: " + active.columnname)
+        return nil
     }
 
     onEdge := false
@@ -813,7 +1054,8 @@ func drawBoard(g *gocui.Gui) error {
         }
         v.Editable = false
         v.Frame = false
-        v.BgColor = gocui.ColorMagenta
+        v.FgColor = theme.status.attribute()
+        v.BgColor = theme.statusBackground.attribute()
         fmt.Fprintln(v, "Loading issues...")
     }
 
@@ -824,14 +1066,14 @@ func quit(g *gocui.Gui, v *gocui.View) error {
     return gocui.ErrQuit
 }
 
-func readConfig() configItem {
+func readConfig() (configItem, error) {
     conf := viper.New()
     conf.SetConfigName("jb")        // name of config file (without extension)
     conf.AddConfigPath("/etc/jb")   // path to look for the config file in
     conf.AddConfigPath("$HOME/.jb") // call multiple times to add many search paths
     err := conf.ReadInConfig()      // Find and read the config file
     if err != nil {                 // Handle errors reading the config file
-        panic(fmt.Errorf("fatal error config file: %s", err))
+        return configItem{}, &jbError{kind: errConfig, err: err}
     }
 
     instanceURL := conf.GetString("jira_instance")
@@ -839,11 +1081,24 @@ func readConfig() configItem {
     password := conf.GetString("jira_password")
     query := conf.GetString("jira_query")
     browserCommand := conf.GetString("browser_command")
+    defaultProject := conf.GetString("default_project")
+    editorCommand := conf.GetString("editor_command")
+    epicLinkField := conf.GetString("epic_link_field")
+    boardID := conf.GetInt("board_id")
+    downloadDir := conf.GetString("download_dir")
+    keymap := conf.GetStringMap("keymap")
+    theme := conf.GetStringMap("theme")
+    mouse := conf.GetBool("mouse")
+    branchTemplate := conf.GetString("branch_template")
     configColumns = conf.GetStringSlice("board_list")
+    colorByEpic = conf.GetBool("color_by_epic")
+    flaggedFirst = conf.GetBool("flagged_first")
 
     if containsEmpty(instanceURL, username, password, query, browserCommand) {
-        fmt.Println("Sorry, couldn't find all required config variables.")
-        printConfigHelp()
+        return configItem{}, &jbError{
+            kind: errConfig,
+            err:  errors.New("Sorry, couldn't find all required config variables."),
+        }
     }
 
     return configItem{
@@ -852,7 +1107,16 @@ func readConfig() configItem {
         password:       password,
         query:          query,
         browserCommand: browserCommand,
-    }
+        defaultProject: defaultProject,
+        editorCommand:  editorCommand,
+        epicLinkField:  epicLinkField,
+        boardID:        boardID,
+        downloadDir:    downloadDir,
+        keymap:         keymap,
+        theme:          theme,
+        mouse:          mouse,
+        branchTemplate: branchTemplate,
+    }, nil
 }
 
 func printVersion() {
@@ -861,8 +1125,7 @@ func printVersion() {
     os.Exit(0)
 }
 
-func printConfigHelp() {
-    fmt.Println(`
+var exampleConfig = `
 Here is an example config, the file should be placed under ~/.jb/jb.yaml or /etc/jb/jb.yaml :
 
 jira_instance: "https://my.jira.instance.address"
@@ -871,7 +1134,28 @@ jira_password: "my.password"
 board_list: ["Open", "In Progress", "On Hold", "Blocked External", "In Review"] # Or whichever statuses you want to display
 browser_command: "/usr/bin/xdg-open" # Or any other specific browser path
 jira_query: "project = TECH AND assignee = my.username AND status not in (Resolved, Closed, Rejected)" # Or any valid JQL
-    `)
+default_project: "TECH" # Optional, where new issues go if the board is empty
+editor_command: "vim" # Optional, used for long texts, $VISUAL or $EDITOR is used otherwise
+epic_link_field: "customfield_10014" # Optional, found by its type if not given
+color_by_epic: false # Optional, colors the cards of the same epic alike
+board_id: 42 # Optional, turns on scrum mode and shows the sprints of this board
+flagged_first: false # Optional, puts the flagged cards on top of their columns
+download_dir: "~/Downloads" # Optional, where attachments are saved
+branch_template: "{{.Key}}-{{slug .Summary}}" # Optional, name of the branches created for issues, .Type is there too
+keymap: # Optional, keys of the actions, see the README for the action names
+  preset: "vim" # Or "default", vim adds h, j, k and l for moving around
+  log-work: "W" # A key, or a list of them like ["w", "Alt-w"]
+mouse: false # Optional, click to select, double click to preview, right click for the menu
+theme: # Optional, colors as names, 0-255 or "#rrggbb"
+  preset: "dark" # Or "light" or "high-contrast"
+  status_background: "blue" # Also frame, background, selection, selection_background, status
+  palette: ["red", "green", "blue"] # Colors for components and epics
+  components: {"Backend": "cyan"}
+  priorities: {"Highest": "red", "High": "#ff8700"}
+    `
+
+func printConfigHelp() {
+    fmt.Println(exampleConfig)
     os.Exit(0)
 }
 
@@ -884,6 +1168,7 @@ func updateStatusBar(g *gocui.Gui, msg string) {
         return
     }
     statusView.Clear()
+    msg = offlineMarker() + sprintMarker() + epicMarker() + markMarker() + msg
     if msg == "" {
         return
     }
@@ -893,8 +1178,16 @@ func updateStatusBar(g *gocui.Gui, msg string) {
 func cursorDown(g *gocui.Gui, v *gocui.View) error {
     if v != nil {
         cx, cy := v.Cursor()
+        // Nothing below, don't wander into the empty space
+        if _, err := v.Line(cy + 1); err != nil {
+            return nil
+        }
         if err := v.SetCursor(cx, cy+1); err != nil {
-            log.Warn("Problem setting the cursor down")
+            // Bottom of the view, scroll the list instead
+            ox, oy := v.Origin()
+            if err := v.SetOrigin(ox, oy+1); err != nil {
+                log.Warn("Problem setting the cursor down")
+            }
         }
     }
     return nil
@@ -902,11 +1195,12 @@ func cursorDown(g *gocui.Gui, v *gocui.View) error {
 
 func cursorUp(g *gocui.Gui, v *gocui.View) error {
     if v != nil {
-        // ox, oy := v.Origin()
-        _, oy := v.Origin()
+        ox, oy := v.Origin()
         cx, cy := v.Cursor()
         if err := v.SetCursor(cx, cy-1); err != nil && oy > 0 {
-            log.Warn("Problem setting the cursor up")
+            if err := v.SetOrigin(ox, oy-1); err != nil {
+                log.Warn("Problem setting the cursor up")
+            }
         }
     }
     return nil
@@ -927,6 +1221,7 @@ func main() {
     var logFile = flag.String("logfile", "/tmp/jb.log", "Location of the log file")
     var version = flag.Bool("version", false, "Show version information")
     var configHelp = flag.Bool("confighelp", false, "Prints the configuration help")
+    var export = flag.String("export", "", "Exports the board to the given .html or .md file")
 
     flag.Usage = func() {
         fmt.Println()
@@ -935,6 +1230,8 @@ func main() {
         fmt.Println("Accepted parameters:")
         fmt.Println()
         flag.PrintDefaults()
+        fmt.Println()
+        printCommands()
     }
 
     flag.Parse()
@@ -962,38 +1259,89 @@ func main() {
         log.Warn("Failed to log to file, using default stderr")
     }
 
-    g, err := gocui.NewGui(gocui.OutputNormal)
+    if flag.NArg() > 0 {
+        os.Exit(runCommand(flag.Args()))
+    }
+    if *export != "" {
+        if err := exportBoard(*export); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(exitCodes[errorKindOf(err)])
+        }
+        os.Exit(0)
+    }
+
+    // Better to complain about the config before we take over the terminal
+    conf, err := readConfig()
+    if err != nil {
+        fmt.Println(err)
+        fmt.Print(exampleConfig)
+        os.Exit(1)
+    }
+    if err := loadKeymap(conf.keymap); err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+    if err := loadTheme(conf.theme); err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+
+    g, err := gocui.NewGui(outputMode)
     if err != nil {
         log.Panicln(err)
     }
     defer g.Close()
 
-    g.Mouse = false
+    g.Mouse = conf.mouse
     g.Highlight = true
     g.Cursor = true
     g.InputEsc=true
-    g.SelFgColor = gocui.ColorBlue
+    applyTheme(g)
     g.SetManagerFunc(drawBoard)
 
-    if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
+    statusNotifier = func(msg string) {
+        g.Update(func(g *gocui.Gui) error {
+            updateStatusBar(g, msg)
+            return nil
+        })
+    }
+
+    if err := bindAllKeys(g); err != nil {
+        log.Panicln(err)
+    }
+    if err := bindMouse(g); err != nil {
+        log.Panicln(err)
+    }
+    if err := g.SetKeybinding("formInput", gocui.KeyCtrlS, gocui.ModNone, saveFormInput); err != nil {
         log.Panicln(err)
     }
-    if err := g.SetKeybinding("menu", gocui.KeyArrowDown, gocui.ModNone, cursorDown); err != nil {
+    if err := g.SetKeybinding("formInput", gocui.KeyCtrlE, gocui.ModNone, formInputInEditor); err != nil {
         log.Panicln(err)
     }
-    if err := g.SetKeybinding("menu", gocui.KeyArrowUp, gocui.ModNone, cursorUp); err != nil {
+    if err := g.SetKeybinding("formInput", gocui.KeyEsc, gocui.ModNone, destroyView); err != nil {
         log.Panicln(err)
     }
-    if err := g.SetKeybinding("menu", gocui.KeyEnter, gocui.ModNone, getMenuSelection); err != nil {
+    if err := g.SetKeybinding("prompt", gocui.KeyEnter, gocui.ModNone, submitPrompt); err != nil {
         log.Panicln(err)
     }
-    if err := g.SetKeybinding("", gocui.KeyF5, gocui.ModNone, refreshBoard); err != nil {
+    if err := g.SetKeybinding("prompt", gocui.KeyEsc, gocui.ModNone, destroyView); err != nil {
+        log.Panicln(err)
+    }
+    if err := g.SetKeybinding("prompt", gocui.KeyTab, gocui.ModNone, completePrompt); err != nil {
         log.Panicln(err)
     }
 
+    // Show what we had last time, until JIRA answers
+    loadCache()
+    loadTimers()
+    g.Update(func(g *gocui.Gui) error {
+        conf, _ := readConfig()
+        return renderCachedBoard(g, conf)
+    })
     g.Update(func(g *gocui.Gui) error {
         return refreshBoard(g, nil)
     })
+    go syncInBackground(g, time.Minute)
 
     if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
         log.Panicln(err)
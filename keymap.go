@@ -0,0 +1,374 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/jroimartin/gocui"
+)
+
+// cardView stands for every card on the board in the keymap, they are
+// bound one by one as they are created.
+var cardView = "card"
+
+// keyAction is something a key does in some views. The same action can
+// be registered for several views with different handlers, like "down"
+// moving between cards on the board and between lines in lists.
+type keyAction struct {
+    name    string
+    help    string
+    keys    []string
+    views   []string
+    handler func(*gocui.Gui, *gocui.View) error
+}
+
+// boundKey is a key name parsed into what gocui wants
+type boundKey struct {
+    name string
+    key  interface{}
+    mod  gocui.Modifier
+}
+
+var keyActions = []keyAction{}
+
+// lists are the views with lines to move between
+var lists = []string{"menu", "picker", "form", "formValues", "previewBox", "backlog", "help"}
+
+// defaultKeyActions is the keymap without any configuration. Editable
+// views keep their Ctrl keys, typing would eat anything else.
+func defaultKeyActions() []keyAction {
+    return []keyAction{
+        {"quit", "Quit", []string{"Ctrl-C"}, []string{""}, quit},
+        {"refresh", "Reload the board", []string{"F5"}, []string{""}, refreshBoard},
+        {"help", "Keys of this view", []string{"?", "F1"}, []string{
+            cardView, "statusLine", "menu", "picker", "form", "formValues", "formInput",
+            "msgBox", "prompt", "previewBox", "backlog", "errorBox", "help",
+        }, toggleHelp},
+
+        {"up", "Previous card", []string{"Up"}, []string{cardView}, upHandler},
+        {"down", "Next card", []string{"Down"}, []string{cardView}, downHandler},
+        {"left", "Card on the left", []string{"Left"}, []string{cardView}, leftHandler},
+        {"right", "Card on the right", []string{"Right"}, []string{cardView}, rightHandler},
+        {"up", "Previous line", []string{"Up"}, lists, cursorUp},
+        {"down", "Next line", []string{"Down"}, lists, cursorDown},
+
+        {"menu", "Actions menu", []string{"Space"}, []string{cardView}, openMenu},
+        {"menu", "Close the menu", []string{"Space"}, []string{"menu"}, destroyView},
+        {"menu", "Actions menu", []string{"Space"}, []string{"backlog"}, backlogMenu},
+        {"select", "Run the action", []string{"Enter"}, []string{"menu"}, getMenuSelection},
+        {"select", "Choose", []string{"Enter"}, []string{"picker"}, pickOption},
+        {"select", "Edit the field", []string{"Enter"}, []string{"form"}, editFormField},
+        {"select", "Choose", []string{"Enter"}, []string{"formValues"}, pickFormValue},
+        {"select", "Close", []string{"Enter"}, []string{"errorBox"}, destroyView},
+        {"select", "Jump to issue, open attachment or link", []string{"Enter"}, []string{"previewBox"}, jumpToIssue},
+        {"select", "Preview", []string{"Enter"}, []string{"backlog"}, backlogPreview},
+        {"submit", "Submit", []string{"Ctrl-S"}, []string{"form"}, submitForm},
+        {"close", "Close", []string{"Esc"}, []string{"menu", "picker", "form", "formValues", "errorBox"}, destroyView},
+        {"close", "Back", []string{"Esc"}, []string{"previewBox"}, closePreview},
+        {"close", "Back to the board", []string{"Esc"}, []string{"backlog"}, closeBacklog},
+        {"close", "Close", []string{"Esc"}, []string{"help"}, closeHelp},
+
+        // Terminal can't tell us about Shift+Arrow, so we use Shift+, and Shift+.
+        {"transition-prev", "Move card to the left", []string{"<"}, []string{cardView}, moveLeftHandler},
+        {"transition-next", "Move card to the right", []string{">"}, []string{cardView}, moveRightHandler},
+        {"new-issue", "New issue", []string{"n"}, []string{cardView, "statusLine"}, newIssueHandler},
+        {"edit", "Edit issue", []string{"e"}, []string{cardView}, editIssueHandler},
+        {"preview", "Preview issue", []string{"p"}, []string{cardView}, directAction("Preview issue")},
+        {"preview", "Preview issue", []string{"p"}, []string{"backlog"}, backlogPreview},
+        {"comment", "Comment on issue", []string{"c"}, []string{cardView}, directAction("Comment on issue")},
+        {"open-browser", "Open in browser", []string{"o"}, []string{cardView}, directAction("Open in browser")},
+        {"log-work", "Log work", []string{"w"}, []string{cardView}, directAction("Log work")},
+        {"timer", "Start or stop the timer", []string{"t"}, []string{cardView}, toggleTimerHandler},
+        {"mark", "Mark card", []string{"x"}, []string{cardView}, markCardHandler},
+        {"mark", "Mark issue", []string{"x"}, []string{"backlog"}, backlogMark},
+        {"mark-column", "Mark the column", []string{"X"}, []string{cardView}, markColumnHandler},
+        {"mark-all", "Mark the board", []string{"Ctrl-A"}, []string{cardView}, markAllHandler},
+        {"epics", "Epics", []string{"E"}, []string{cardView}, openEpicPanel},
+        {"sprints", "Switch sprint", []string{"S"}, []string{cardView, "statusLine"}, switchSprintHandler},
+        {"backlog", "Backlog", []string{"B"}, []string{cardView, "statusLine", "backlog"}, toggleBacklog},
+        {"export", "Export the board", []string{"P"}, []string{cardView, "statusLine"}, exportBoardHandler},
+        {"search", "Search", []string{"/"}, []string{"backlog"}, backlogSearchHandler},
+        {"move-to-column", "Move to a column", []string{"m"}, []string{"backlog"}, backlogMoveHandler},
+        {"add-link", "Add link", []string{"l"}, []string{"previewBox"}, addLinkHandler},
+        {"remove-link", "Remove link", []string{"d"}, []string{"previewBox"}, removeLinkHandler},
+        {"save-attachment", "Save attachment", []string{"s"}, []string{"previewBox"}, saveAttachmentHandler},
+        {"attach-file", "Attach file", []string{"u"}, []string{"previewBox"}, uploadAttachmentHandler},
+    }
+}
+
+// keyPresets are added to the defaults, before the configured keys
+var keyPresets = map[string]map[string][]string{
+    "default": {},
+    "vim": {
+        "up":    {"k", "Up"},
+        "down":  {"j", "Down"},
+        "left":  {"h", "Left"},
+        "right": {"l", "Right"},
+    },
+}
+
+// directAction runs a line of the actions menu on the active card,
+// without opening the menu.
+func directAction(line string) func(*gocui.Gui, *gocui.View) error {
+    return func(g *gocui.Gui, v *gocui.View) error {
+        currentColumn, err := getColumn(active.columnname)
+        if err != nil || len(currentColumn.members) == 0 {
+            return nil
+        }
+        menuIssue = currentColumn.members[active.indexno].issue
+        return runMenuAction(g, menuIssue, line)
+    }
+}
+
+var namedKeys = map[string]gocui.Key{
+    "Up":        gocui.KeyArrowUp,
+    "Down":      gocui.KeyArrowDown,
+    "Left":      gocui.KeyArrowLeft,
+    "Right":     gocui.KeyArrowRight,
+    "Enter":     gocui.KeyEnter,
+    "Esc":       gocui.KeyEsc,
+    "Space":     gocui.KeySpace,
+    "Tab":       gocui.KeyTab,
+    "Backspace": gocui.KeyBackspace2,
+    "Insert":    gocui.KeyInsert,
+    "Delete":    gocui.KeyDelete,
+    "Home":      gocui.KeyHome,
+    "End":       gocui.KeyEnd,
+    "PgUp":      gocui.KeyPgup,
+    "PgDn":      gocui.KeyPgdn,
+    "F1":        gocui.KeyF1,
+    "F2":        gocui.KeyF2,
+    "F3":        gocui.KeyF3,
+    "F4":        gocui.KeyF4,
+    "F5":        gocui.KeyF5,
+    "F6":        gocui.KeyF6,
+    "F7":        gocui.KeyF7,
+    "F8":        gocui.KeyF8,
+    "F9":        gocui.KeyF9,
+    "F10":       gocui.KeyF10,
+    "F11":       gocui.KeyF11,
+    "F12":       gocui.KeyF12,
+}
+
+// parseKey understands "j", "Space", "F5", "Ctrl-A" and "Alt-x"
+func parseKey(name string) (boundKey, error) {
+
+    if key, ok := namedKeys[name]; ok {
+        return boundKey{name: name, key: key, mod: gocui.ModNone}, nil
+    }
+    if strings.HasPrefix(name, "Ctrl-") && len(name) == 6 {
+        letter := strings.ToLower(name[5:])[0]
+        if letter >= 'a' && letter <= 'z' {
+            return boundKey{name: name, key: gocui.KeyCtrlA + gocui.Key(letter-'a'), mod: gocui.ModNone}, nil
+        }
+    }
+    if strings.HasPrefix(name, "Alt-") && utf8.RuneCountInString(name) == 5 {
+        r, _ := utf8.DecodeRuneInString(name[4:])
+        return boundKey{name: name, key: r, mod: gocui.ModAlt}, nil
+    }
+    if utf8.RuneCountInString(name) == 1 {
+        r, _ := utf8.DecodeRuneInString(name)
+        return boundKey{name: name, key: r, mod: gocui.ModNone}, nil
+    }
+    return boundKey{}, errors.New("unknown key \"" + name + "\"")
+}
+
+// configuredKeys reads the keys of the actions in the keymap section,
+// a single key or a list of them.
+func configuredKeys(section map[string]interface{}) map[string][]string {
+    keys := map[string][]string{}
+    for name, value := range section {
+        if name == "preset" {
+            continue
+        }
+        switch value := value.(type) {
+        case string:
+            keys[name] = []string{value}
+        case []interface{}:
+            for _, key := range value {
+                keys[name] = append(keys[name], fmt.Sprint(key))
+            }
+        }
+    }
+    return keys
+}
+
+// fixedKeys belong to the views we type in, keys working everywhere
+// would run together with them.
+var fixedKeys = []string{"Enter", "Esc", "Tab", "Ctrl-S", "Ctrl-E"}
+
+// loadKeymap builds the keymap from the defaults, the preset and the
+// keymap section of the configuration, and tells about every problem in
+// it at once.
+func loadKeymap(section map[string]interface{}) error {
+
+    actions := defaultKeyActions()
+    known := map[string]bool{}
+    for _, action := range actions {
+        known[action.name] = true
+    }
+
+    problems := []string{}
+    preset := "default"
+    if name, ok := section["preset"]; ok {
+        preset = fmt.Sprint(name)
+    }
+    presetKeys, ok := keyPresets[preset]
+    if !ok {
+        problems = append(problems, "unknown keymap preset \""+preset+"\"")
+    }
+
+    configured := configuredKeys(section)
+    for name := range configured {
+        if !known[name] {
+            problems = append(problems, "unknown action \""+name+"\" in keymap")
+        }
+    }
+
+    for i := range actions {
+        if keys, ok := presetKeys[actions[i].name]; ok {
+            actions[i].keys = keys
+        }
+        if keys, ok := configured[actions[i].name]; ok {
+            actions[i].keys = keys
+        }
+    }
+
+    // Which action has each key, view by view
+    owners := map[string]map[string]string{}
+    for _, action := range actions {
+        for _, keyName := range action.keys {
+            key, err := parseKey(keyName)
+            if err != nil {
+                problems = append(problems, action.name+": "+err.Error())
+                continue
+            }
+            // Anything else would be eaten while typing a comment
+            if action.views[0] == "" && key.mod == gocui.ModNone {
+                if _, isRune := key.key.(rune); isRune {
+                    problems = append(problems, action.name+": \""+keyName+"\" works everywhere, it can't be a plain character")
+                }
+            }
+            if action.views[0] == "" && indexOf(keyName, fixedKeys) != -1 {
+                problems = append(problems, action.name+": \""+keyName+"\" is needed while typing")
+            }
+            for _, view := range action.views {
+                if owners[view] == nil {
+                    owners[view] = map[string]string{}
+                }
+                if owner, ok := owners[view][keyName]; ok && owner != action.name {
+                    problems = append(problems, "\""+keyName+"\" is used for both "+owner+" and "+action.name)
+                }
+                owners[view][keyName] = action.name
+            }
+        }
+    }
+    // Keys working everywhere can't be used anywhere else
+    for keyName, owner := range owners[""] {
+        for view := range owners {
+            if other, ok := owners[view][keyName]; ok && view != "" {
+                problems = append(problems, "\""+keyName+"\" is used for both "+owner+" and "+other)
+            }
+        }
+    }
+
+    if len(problems) > 0 {
+        sort.Strings(problems)
+        unique := []string{}
+        for i, problem := range problems {
+            if i == 0 || problem != problems[i-1] {
+                unique = append(unique, problem)
+            }
+        }
+        return &jbError{kind: errConfig, err: errors.New("Problems in the keymap:\n  " + strings.Join(unique, "\n  "))}
+    }
+
+    keyActions = actions
+    buildInfoTexts()
+    return nil
+}
+
+// bindKeys binds the keys the keymap has for keymapName to the view,
+// they differ for the cards only.
+func bindKeys(g *gocui.Gui, viewName string, keymapName string) error {
+    for _, action := range keyActions {
+        for _, view := range action.views {
+            if view != keymapName {
+                continue
+            }
+            for _, keyName := range boundKeys(action, keymapName) {
+                key, _ := parseKey(keyName)
+                if err := g.SetKeybinding(viewName, key.key, key.mod, action.handler); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// bindAllKeys binds everything in the keymap, except the cards
+func bindAllKeys(g *gocui.Gui) error {
+    views := map[string]bool{}
+    for _, action := range keyActions {
+        for _, view := range action.views {
+            views[view] = true
+        }
+    }
+    for view := range views {
+        if view == cardView {
+            continue
+        }
+        if err := bindKeys(g, view, view); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// keyHint tells which keys run the action, for the status bar
+func keyHint(name string) string {
+    for _, action := range keyActions {
+        if action.name == name {
+            return strings.Join(action.keys, "/")
+        }
+    }
+    return ""
+}
+
+// buildInfoTexts writes the status bar helps with the keys in use
+func buildInfoTexts() {
+    infoText = "Navigation: " + keyHint("up") + " " + keyHint("down") + " " + keyHint("left") + " " + keyHint("right") +
+        "  |  Mark: " + keyHint("mark") + ", column: " + keyHint("mark-column") + ", all: " + keyHint("mark-all") +
+        "  |  Move card: " + keyHint("transition-prev") + " " + keyHint("transition-next") +
+        "  |  New issue: " + keyHint("new-issue") +
+        "  |  Edit: " + keyHint("edit") +
+        "  |  Timer: " + keyHint("timer") +
+        "  |  Epics: " + keyHint("epics") +
+        "  |  Sprints: " + keyHint("sprints") +
+        "  |  Backlog: " + keyHint("backlog") +
+        "  |  Export: " + keyHint("export") +
+        "  |  Actions Menu: " + keyHint("menu") +
+        "  |  Help: " + keyHint("help") +
+        "  |  Exit: " + keyHint("quit") +
+        " | Reload: " + keyHint("refresh")
+    previewInfoText = "Jump to issue, open attachment or link: " + keyHint("select") +
+        "  |  Add link: " + keyHint("add-link") +
+        "  |  Remove link: " + keyHint("remove-link") +
+        "  |  Save attachment: " + keyHint("save-attachment") +
+        "  |  Attach file: " + keyHint("attach-file") +
+        "  |  Back: " + keyHint("close")
+    backlogInfoText = "Actions: " + keyHint("menu") +
+        "  |  Preview: " + keyHint("preview") +
+        "  |  Search: " + keyHint("search") +
+        "  |  Mark: " + keyHint("mark") +
+        "  |  Move to column: " + keyHint("move-to-column") +
+        "  |  Board: " + keyHint("backlog") + " or " + keyHint("close")
+    formInfoText = "Edit field: " + keyHint("select") +
+        "  |  Submit: " + keyHint("submit") +
+        "  |  Cancel: " + keyHint("close") +
+        "  |  Required fields are marked with *"
+}
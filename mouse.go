@@ -0,0 +1,207 @@
+package main
+
+import (
+    "time"
+
+    "github.com/jroimartin/gocui"
+)
+
+// doubleClickTime is how close two clicks on the same line have to be
+var doubleClickTime = 400 * time.Millisecond
+
+// lastClick is the view and line clicked last, to find double clicks
+var lastClick = struct {
+    view string
+    line int
+    at   time.Time
+}{}
+
+// listClicks are what a click and a double click do in the lists, the
+// same as Enter does there.
+var listClicks = map[string][2]func(*gocui.Gui, *gocui.View) error{
+    "menu":       {getMenuSelection, nil},
+    "picker":     {pickOption, nil},
+    "formValues": {pickFormValue, nil},
+    "errorBox":   {destroyView, nil},
+    "form":       {nil, editFormField},
+    "backlog":    {nil, backlogPreview},
+    "previewBox": {nil, jumpToIssue},
+    "help":       {nil, nil},
+}
+
+// doubleClick remembers the click, and tells if it is the second one on
+// the same line.
+func doubleClick(v *gocui.View) bool {
+    _, cy := v.Cursor()
+    _, oy := v.Origin()
+    double := lastClick.view == v.Name() && lastClick.line == cy+oy && time.Since(lastClick.at) < doubleClickTime
+    lastClick.view = v.Name()
+    lastClick.line = cy + oy
+    lastClick.at = time.Now()
+    if double {
+        lastClick.view = ""
+    }
+    return double
+}
+
+// boardFocused tells if the board has the focus, nothing is open on it
+func boardFocused(g *gocui.Gui) bool {
+    current := g.CurrentView()
+    return current == nil || current.Name() == "statusLine" || keymapView(current.Name()) == cardView
+}
+
+// selectCard makes the card the active one, like the arrow keys would
+func selectCard(g *gocui.Gui, key string) bool {
+    for i := range kanbanMatrix {
+        index := indexOfView(key, kanbanMatrix[i].members)
+        if index < 0 {
+            continue
+        }
+        if kanbanMatrix[i].view.Title != active.columnname {
+            // Only the active column is ever scrolled
+            moveIssues(g, 1000, true)
+        }
+        active.columnname = kanbanMatrix[i].view.Title
+        active.issuetitle = key
+        active.indexno = index
+        setCurrentViewOnTop(g, key)
+        g.SetViewOnTop("statusLine")
+        updateStatusBar(g, "")
+        return true
+    }
+    return false
+}
+
+// selectColumn activates the first card of a column clicked on its
+// empty part. Clicking the active column changes nothing.
+func selectColumn(g *gocui.Gui, name string) bool {
+    if name == active.columnname {
+        return true
+    }
+    currentColumn, err := getColumn(name)
+    if err != nil || len(currentColumn.members) == 0 {
+        return false
+    }
+    return selectCard(g, currentColumn.members[0].view.Title)
+}
+
+func clickCard(g *gocui.Gui, v *gocui.View) error {
+    if !boardFocused(g) || !selectCard(g, v.Name()) {
+        return nil
+    }
+    if doubleClick(v) {
+        return directAction("Preview issue")(g, v)
+    }
+    return nil
+}
+
+func rightClickCard(g *gocui.Gui, v *gocui.View) error {
+    if !boardFocused(g) || !selectCard(g, v.Name()) {
+        return nil
+    }
+    return openMenu(g, v)
+}
+
+func clickColumn(g *gocui.Gui, v *gocui.View) error {
+    if boardFocused(g) {
+        selectColumn(g, v.Name())
+    }
+    return nil
+}
+
+// wheelBoard scrolls the column under the pointer, one card at a time
+func wheelBoard(direction string) func(*gocui.Gui, *gocui.View) error {
+    return func(g *gocui.Gui, v *gocui.View) error {
+        if !boardFocused(g) {
+            return nil
+        }
+        if !selectCard(g, v.Name()) && !selectColumn(g, v.Name()) {
+            return nil
+        }
+        return upDownView(g, v, direction)
+    }
+}
+
+// clickList runs what Enter does in the list, on a single or a double
+// click depending on the list. Lists under the focused one are left
+// alone.
+func clickList(g *gocui.Gui, v *gocui.View) error {
+    if g.CurrentView() != v {
+        return nil
+    }
+    handlers := listClicks[v.Name()]
+    if doubleClick(v) && handlers[1] != nil {
+        return handlers[1](g, v)
+    }
+    if handlers[0] != nil {
+        return handlers[0](g, v)
+    }
+    return nil
+}
+
+func rightClickBacklog(g *gocui.Gui, v *gocui.View) error {
+    if g.CurrentView() != v {
+        return nil
+    }
+    return backlogMenu(g, v)
+}
+
+func wheelList(handler func(*gocui.Gui, *gocui.View) error) func(*gocui.Gui, *gocui.View) error {
+    return func(g *gocui.Gui, v *gocui.View) error {
+        if g.CurrentView() != v {
+            return nil
+        }
+        return handler(g, v)
+    }
+}
+
+// bindCardMouse binds the mouse on a card or a column
+func bindCardMouse(g *gocui.Gui, viewName string, click func(*gocui.Gui, *gocui.View) error, rightClick func(*gocui.Gui, *gocui.View) error) error {
+    if !g.Mouse {
+        return nil
+    }
+    bindings := map[gocui.Key]func(*gocui.Gui, *gocui.View) error{
+        gocui.MouseLeft:      click,
+        gocui.MouseRight:     rightClick,
+        gocui.MouseWheelDown: wheelBoard("down"),
+        gocui.MouseWheelUp:   wheelBoard("up"),
+    }
+    for key, handler := range bindings {
+        if handler == nil {
+            continue
+        }
+        if err := g.SetKeybinding(viewName, key, gocui.ModNone, handler); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// bindMouse binds the mouse on the columns and the lists, cards get
+// theirs when they are created.
+func bindMouse(g *gocui.Gui) error {
+    if !g.Mouse {
+        return nil
+    }
+    for _, columnName := range configColumns {
+        if err := bindCardMouse(g, columnName, clickColumn, nil); err != nil {
+            return err
+        }
+    }
+    for viewName := range listClicks {
+        bindings := map[gocui.Key]func(*gocui.Gui, *gocui.View) error{
+            gocui.MouseLeft:      clickList,
+            gocui.MouseWheelDown: wheelList(cursorDown),
+            gocui.MouseWheelUp:   wheelList(cursorUp),
+        }
+        if viewName == "backlog" {
+            bindings[gocui.MouseRight] = rightClickBacklog
+        }
+        for key, handler := range bindings {
+            if err := g.SetKeybinding(viewName, key, gocui.ModNone, handler); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+    "errors"
+    "sort"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// cardMove describes a card travelling between two adjacent columns
+type cardMove struct {
+    issueID     string
+    issueKey    string
+    fromColumn  string
+    toColumn    string
+    fromIndex   int
+    transitions map[string]jira.Transition
+}
+
+func moveRightHandler(g *gocui.Gui, v *gocui.View) error {
+    return moveCard(g, "right")
+}
+
+func moveLeftHandler(g *gocui.Gui, v *gocui.View) error {
+    return moveCard(g, "left")
+}
+
+// adjacentColumn returns the column next to the given one. Unlike the
+// navigation, we don't wrap around the edges of the board here.
+func adjacentColumn(name string, direction string) (*column, error) {
+    for i := range kanbanMatrix {
+        if kanbanMatrix[i].view.Title != name {
+            continue
+        }
+        if direction == "right" && i < len(kanbanMatrix)-1 {
+            return &kanbanMatrix[i+1], nil
+        }
+        if direction == "left" && i > 0 {
+            return &kanbanMatrix[i-1], nil
+        }
+        return &column{}, errors.New("There is no column on the " + direction + " of " + name)
+    }
+    return &column{}, errors.New("Couldn't find column with given title")
+}
+
+// moveCard sends the active issue to the adjacent column, by finding
+// the workflow transition which leads to the status of that column.
+func moveCard(g *gocui.Gui, direction string) error {
+
+    currentColumn, err := getColumn(active.columnname)
+    if err != nil || len(currentColumn.members) == 0 {
+        return nil
+    }
+
+    targetColumn, err := adjacentColumn(active.columnname, direction)
+    if err != nil {
+        updateStatusBar(g, err.Error())
+        return nil
+    }
+
+    issue := currentColumn.members[active.indexno].issue
+    availTransitions, err := getTransitions(issue.ID)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    move := cardMove{
+        issueID:     issue.ID,
+        issueKey:    issue.Key,
+        fromColumn:  currentColumn.view.Title,
+        toColumn:    targetColumn.view.Title,
+        fromIndex:   active.indexno,
+        transitions: map[string]jira.Transition{},
+    }
+    for k := range availTransitions {
+        if availTransitions[k].To.Name == move.toColumn {
+            move.transitions[availTransitions[k].Name] = availTransitions[k]
+        }
+    }
+
+    switch len(move.transitions) {
+    case 0:
+        updateStatusBar(g, "No transition leads "+issue.Key+" to "+move.toColumn)
+    case 1:
+        for _, transition := range move.transitions {
+            return applyCardMove(g, move, transition)
+        }
+    default:
+        return pickTransitionForMove(g, move)
+    }
+
+    return nil
+}
+
+// pickTransitionForMove asks which transition should be used, when
+// multiple ones reach the same column.
+func pickTransitionForMove(g *gocui.Gui, move cardMove) error {
+
+    names := make([]string, 0, len(move.transitions))
+    for k := range move.transitions {
+        names = append(names, k)
+    }
+    sort.Strings(names)
+
+    return openPicker(g, "Move "+move.issueKey+" to "+move.toColumn+" via", names, func(g *gocui.Gui, choice string) error {
+        return applyCardMove(g, move, move.transitions[choice])
+    })
+}
+
+// applyCardMove asks for the transition screen if there is one, and
+// starts moving the card.
+func applyCardMove(g *gocui.Gui, move cardMove, transition jira.Transition) error {
+    if len(transition.Fields) > 0 {
+        return openTransitionForm(g, move.issueID, move.issueKey, transition, func(g *gocui.Gui, payload interface{}) error {
+            return startCardMove(g, move, payload)
+        })
+    }
+    return startCardMove(g, move, jira.CreateTransitionPayload{
+        Transition: jira.TransitionPayload{ID: transition.ID},
+    })
+}
+
+// startCardMove moves the card on the board right away and runs the
+// transition in background. If JIRA refuses it, the card is put back.
+func startCardMove(g *gocui.Gui, move cardMove, payload interface{}) error {
+
+    if err := relocateCard(g, move.issueKey, move.fromColumn, move.toColumn, -1); err != nil {
+        updateStatusBar(g, err.Error())
+        return nil
+    }
+
+    updateStatusBar(g, "Moving "+move.issueKey+" to "+move.toColumn+"...")
+
+    // Logging in replaces jiraClient, the goroutine gets the client as it
+    // is now
+    authErr := ensureAuthenticated()
+    client := jiraClient
+    go func() {
+        err := authErr
+        if err == nil {
+            var res *jira.Response
+            res, err = client.Issue.DoTransitionWithPayload(move.issueID, payload)
+            err = newJiraError(res, err)
+        }
+        g.Update(func(g *gocui.Gui) error {
+            if isOffline(err) {
+                // The card is already where it should be
+                return queueTransition(g, move.issueID, move.issueKey, move.toColumn, payload)
+            }
+            if err != nil {
+                log.Warn("Transition failed for " + move.issueKey + ": " + err.Error())
+                relocateCard(g, move.issueKey, move.toColumn, move.fromColumn, move.fromIndex)
+                updateStatusBar(g, "Couldn't move "+move.issueKey+", reverted: "+err.Error())
+                return nil
+            }
+            saveBoardCache()
+            updateStatusBar(g, move.issueKey+" moved to "+move.toColumn)
+            return nil
+        })
+    }()
+
+    return nil
+}
+
+// relocateCard takes the card out of one column and places it into
+// another one at given index. Negative index means the end of column.
+func relocateCard(g *gocui.Gui, key string, fromName string, toName string, index int) error {
+
+    fromColumn, err := getColumn(fromName)
+    if err != nil {
+        return err
+    }
+    toColumn, err := getColumn(toName)
+    if err != nil {
+        return err
+    }
+
+    pos := indexOfView(key, fromColumn.members)
+    if pos < 0 {
+        return errors.New("Couldn't find " + key + " in column " + fromName)
+    }
+
+    // Columns are laid out from the top again, so reset the scrolling
+    moveIssues(g, 1000, true)
+
+    box := fromColumn.members[pos]
+    fromColumn.members = append(fromColumn.members[:pos], fromColumn.members[pos+1:]...)
+
+    status := *box.issue.Fields.Status
+    status.Name = toName
+    box.issue.Fields.Status = &status
+
+    if index < 0 || index > len(toColumn.members) {
+        index = len(toColumn.members)
+    }
+    toColumn.members = append(toColumn.members, issueBox{})
+    copy(toColumn.members[index+1:], toColumn.members[index:])
+    toColumn.members[index] = box
+
+    relayoutColumn(g, fromColumn)
+    relayoutColumn(g, toColumn)
+
+    if active.issuetitle == key {
+        active.columnname = toName
+    }
+    if col, err := getColumn(active.columnname); err == nil {
+        active.indexno = indexOfView(active.issuetitle, col.members)
+    }
+    if active.indexno < 0 {
+        activateFirstIssue(g)
+    }
+
+    // Scroll down again if the active issue is below the screen now
+    for i := 0; i < active.indexno-4; i++ {
+        moveIssues(g, -6, false)
+    }
+
+    if active.issuetitle == key && boardHasFocus(g) {
+        setCurrentViewOnTop(g, key)
+    }
+
+    g.SetViewOnTop("statusLine")
+    return nil
+}
+
+// relayoutColumn places the members of the column one under another,
+// starting from the top of the column.
+func relayoutColumn(g *gocui.Gui, col *column) error {
+    for i := range col.members {
+        coords, err := giveNextIssueCoord(g, column{view: col.view, members: col.members[:i]})
+        if err != nil {
+            return err
+        }
+        if _, err := g.SetView(
+            col.members[i].view.Title, coords[0], coords[1], coords[2], coords[3]); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// boardHasFocus tells if the user is on the board, not in a popup
+func boardHasFocus(g *gocui.Gui) bool {
+    v := g.CurrentView()
+    if v == nil {
+        return true
+    }
+    for i := range kanbanMatrix {
+        if indexOfView(v.Name(), kanbanMatrix[i].members) >= 0 {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+    "errors"
+    "sort"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+type createMetaIssueType struct {
+    ID      string               `json:"id"`
+    Name    string               `json:"name"`
+    Subtask bool                 `json:"subtask"`
+    Fields  map[string]fieldMeta `json:"fields"`
+}
+
+type createMetaProject struct {
+    ID         string                `json:"id"`
+    Key        string                `json:"key"`
+    Name       string                `json:"name"`
+    IssueTypes []createMetaIssueType `json:"issuetypes"`
+}
+
+// createFormFields are offered on the new issue form even if they are
+// not required, as long as the project has them.
+var createFormFields = []string{
+    "summary",
+    "description",
+    "priority",
+    "components",
+    "labels",
+    "assignee",
+}
+
+// getCreateMeta fetches what can be created in the project. Like the
+// transition fields, go-jira's own type is not enough for a form.
+func getCreateMeta(projectKey string) (createMetaProject, error) {
+
+    if err := ensureAuthenticated(); err != nil {
+        return createMetaProject{}, err
+    }
+
+    req, err := jiraClient.NewRequest(
+        "GET",
+        "rest/api/2/issue/createmeta?expand=projects.issuetypes.fields&projectKeys="+projectKey,
+        nil,
+    )
+    if err != nil {
+        return createMetaProject{}, err
+    }
+
+    result := struct {
+        Projects []createMetaProject `json:"projects"`
+    }{}
+    if res, err := jiraClient.Do(req, &result); err != nil {
+        return createMetaProject{}, newJiraError(res, err)
+    }
+
+    for _, project := range result.Projects {
+        if project.Key == projectKey {
+            return project, nil
+        }
+    }
+    return createMetaProject{}, &jbError{
+        kind: errPermission,
+        err:  errors.New("You can't create issues in project " + projectKey),
+    }
+}
+
+// boardProjects lists the projects we know of, the ones on the board
+// and the configured default.
+func boardProjects() []string {
+    projects := []string{}
+    if conf, err := readConfig(); err == nil && conf.defaultProject != "" {
+        projects = append(projects, conf.defaultProject)
+    }
+    for i := range kanbanMatrix {
+        for _, box := range kanbanMatrix[i].members {
+            key := box.issue.Fields.Project.Key
+            if key != "" && indexOf(key, projects) < 0 {
+                projects = append(projects, key)
+            }
+        }
+    }
+    return projects
+}
+
+func newIssueHandler(g *gocui.Gui, v *gocui.View) error {
+
+    projects := boardProjects()
+    switch len(projects) {
+    case 0:
+        showError(g, &jbError{
+            kind: errConfig,
+            err:  errors.New("No project to create the issue in, please set default_project"),
+        })
+        return nil
+    case 1:
+        return chooseIssueType(g, projects[0])
+    }
+
+    sort.Strings(projects)
+    return openPicker(g, "New issue in project", projects, chooseIssueType)
+}
+
+func chooseIssueType(g *gocui.Gui, projectKey string) error {
+
+    project, err := getCreateMeta(projectKey)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    issueTypes := map[string]createMetaIssueType{}
+    names := []string{}
+    for _, issueType := range project.IssueTypes {
+        // Subtasks need a parent, they can't be created from here
+        if issueType.Subtask {
+            continue
+        }
+        issueTypes[issueType.Name] = issueType
+        names = append(names, issueType.Name)
+    }
+    sort.Strings(names)
+
+    return openPicker(g, "Type of the new "+projectKey+" issue", names, func(g *gocui.Gui, choice string) error {
+        return openCreateForm(g, project, issueTypes[choice])
+    })
+}
+
+func openCreateForm(g *gocui.Gui, project createMetaProject, issueType createMetaIssueType) error {
+
+    metas := map[string]fieldMeta{}
+    for id, meta := range issueType.Fields {
+        // We fill these ourselves
+        if id == "project" || id == "issuetype" {
+            continue
+        }
+        if meta.Required || indexOf(id, createFormFields) >= 0 {
+            metas[id] = meta
+        }
+    }
+
+    return openForm(g, &fieldForm{
+        title:  "New " + issueType.Name + " in " + project.Key,
+        fields: formFieldsFromMeta(metas),
+        submit: func(g *gocui.Gui, form *fieldForm) error {
+            fields := map[string]interface{}{
+                "project":   map[string]string{"id": project.ID},
+                "issuetype": map[string]string{"id": issueType.ID},
+            }
+            for _, f := range form.fields {
+                if f.value == "" {
+                    continue
+                }
+                value, err := fieldValue(f)
+                if err != nil {
+                    return err
+                }
+                fields[f.id] = value
+            }
+            return createJiraIssue(g, fields)
+        },
+    })
+}
+
+// createJiraIssue creates the issue and puts it on the board
+func createJiraIssue(g *gocui.Gui, fields map[string]interface{}) error {
+
+    req, err := jiraClient.NewRequest("POST", "rest/api/2/issue", map[string]interface{}{"fields": fields})
+    if err != nil {
+        return err
+    }
+    created := jira.Issue{}
+    if res, err := jiraClient.Do(req, &created); err != nil {
+        return newJiraError(res, err)
+    }
+
+    // The answer only has the key, fetch the rest for the card
+    issue, res, err := jiraClient.Issue.Get(created.Key, nil)
+    if err != nil {
+        showError(g, newJiraError(res, err))
+        return nil
+    }
+
+    if _, err := getColumn(issue.Fields.Status.Name); err != nil {
+        updateStatusBar(g, issue.Key+" is created, but "+issue.Fields.Status.Name+" is not on the board")
+        return nil
+    }
+
+    if err := createIssue(g, *issue); err != nil {
+        return err
+    }
+    focusCard(g, issue.Key)
+    saveBoardCache()
+
+    updateStatusBar(g, issue.Key+" is created")
+    return nil
+}
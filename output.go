@@ -0,0 +1,225 @@
+package main
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    yaml "gopkg.in/yaml.v2"
+)
+
+// outputFields are the fields of the cards which can be written out
+var outputFields = map[string]func(issue jira.Issue) string{
+    "key":     func(issue jira.Issue) string { return issue.Key },
+    "summary": func(issue jira.Issue) string { return issue.Fields.Summary },
+    "status": func(issue jira.Issue) string {
+        if issue.Fields.Status == nil {
+            return ""
+        }
+        return issue.Fields.Status.Name
+    },
+    "type": func(issue jira.Issue) string { return issue.Fields.Type.Name },
+    "priority": func(issue jira.Issue) string {
+        if issue.Fields.Priority == nil {
+            return ""
+        }
+        return issue.Fields.Priority.Name
+    },
+    "assignee": func(issue jira.Issue) string {
+        if issue.Fields.Assignee == nil {
+            return ""
+        }
+        return issue.Fields.Assignee.Name
+    },
+    "reporter": func(issue jira.Issue) string {
+        if issue.Fields.Reporter == nil {
+            return ""
+        }
+        return issue.Fields.Reporter.Name
+    },
+    "labels": func(issue jira.Issue) string { return strings.Join(issue.Fields.Labels, ",") },
+    "components": func(issue jira.Issue) string {
+        names := []string{}
+        for _, component := range issue.Fields.Components {
+            names = append(names, component.Name)
+        }
+        return strings.Join(names, ",")
+    },
+    "epic": func(issue jira.Issue) string {
+        if epicKey := epicOf(issue); epicKey != "" {
+            return epicName(epicKey)
+        }
+        return ""
+    },
+    "flagged": func(issue jira.Issue) string { return fmt.Sprint(isFlagged(issue)) },
+    "blocked": func(issue jira.Issue) string { return fmt.Sprint(isBlocked(issue)) },
+    "time":    timeTracking,
+}
+
+var defaultOutputFields = []string{"key", "summary", "assignee", "priority"}
+
+var outputFormats = []string{"json", "yaml", "csv", "tsv", "markdown"}
+
+// boardGroup is a column of the board with its issues, in the order jb
+// shows them.
+type boardGroup struct {
+    name   string
+    issues []jira.Issue
+}
+
+// groupIssues puts the issues into the columns of board_list, the ones
+// in other statuses are left out like on the board.
+func groupIssues(issues []jira.Issue) []boardGroup {
+    if flaggedFirst {
+        issues = sortFlaggedFirst(issues)
+    }
+    groups := []boardGroup{}
+    for _, name := range configColumns {
+        group := boardGroup{name: name, issues: []jira.Issue{}}
+        for _, issue := range issues {
+            if issue.Fields.Status != nil && issue.Fields.Status.Name == name {
+                group.issues = append(group.issues, issue)
+            }
+        }
+        groups = append(groups, group)
+    }
+    return groups
+}
+
+// parseFields checks the comma separated list of fields
+func parseFields(list string) ([]string, error) {
+    if list == "" {
+        return defaultOutputFields, nil
+    }
+    fields := []string{}
+    for _, field := range strings.Split(list, ",") {
+        field = strings.ToLower(strings.TrimSpace(field))
+        if _, ok := outputFields[field]; !ok {
+            return nil, usageError("unknown field \"" + field + "\"")
+        }
+        fields = append(fields, field)
+    }
+    return fields, nil
+}
+
+func cardValues(issue jira.Issue, fields []string) []string {
+    values := []string{}
+    for _, field := range fields {
+        values = append(values, outputFields[field](issue))
+    }
+    return values
+}
+
+// outputColumn is how a group looks in json and yaml
+type outputColumn struct {
+    Column string          `json:"column" yaml:"column"`
+    Count  int             `json:"count" yaml:"count"`
+    Cards  []yaml.MapSlice `json:"cards" yaml:"cards"`
+}
+
+// MarshalJSON keeps the fields of the cards in the order they were asked
+func (column outputColumn) MarshalJSON() ([]byte, error) {
+    cards := []json.RawMessage{}
+    for _, card := range column.Cards {
+        parts := []string{}
+        for _, item := range card {
+            key, err := json.Marshal(item.Key)
+            if err != nil {
+                return nil, err
+            }
+            value, err := json.Marshal(item.Value)
+            if err != nil {
+                return nil, err
+            }
+            parts = append(parts, string(key)+":"+string(value))
+        }
+        cards = append(cards, json.RawMessage("{"+strings.Join(parts, ",")+"}"))
+    }
+    return json.Marshal(struct {
+        Column string            `json:"column"`
+        Count  int               `json:"count"`
+        Cards  []json.RawMessage `json:"cards"`
+    }{column.Column, column.Count, cards})
+}
+
+func outputColumns(groups []boardGroup, fields []string) []outputColumn {
+    columns := []outputColumn{}
+    for _, group := range groups {
+        column := outputColumn{Column: group.name, Count: len(group.issues), Cards: []yaml.MapSlice{}}
+        for _, issue := range group.issues {
+            card := yaml.MapSlice{}
+            for i, value := range cardValues(issue, fields) {
+                card = append(card, yaml.MapItem{Key: fields[i], Value: value})
+            }
+            column.Cards = append(column.Cards, card)
+        }
+        columns = append(columns, column)
+    }
+    return columns
+}
+
+// markdownCell keeps the text inside its cell of the table
+func markdownCell(text string) string {
+    text = strings.Replace(text, "|", "\\|", -1)
+    text = strings.Replace(text, "<", "&lt;", -1)
+    return strings.Join(strings.Fields(text), " ")
+}
+
+func writeMarkdown(w io.Writer, groups []boardGroup, fields []string) {
+    header := []string{}
+    for _, field := range fields {
+        header = append(header, strings.Title(field))
+    }
+    for i, group := range groups {
+        if i > 0 {
+            fmt.Fprintln(w)
+        }
+        fmt.Fprintf(w, "## %s (%d)\n\n", group.name, len(group.issues))
+        if len(group.issues) == 0 {
+            fmt.Fprintln(w, "_No issues_")
+            continue
+        }
+        fmt.Fprintln(w, "| "+strings.Join(header, " | ")+" |")
+        fmt.Fprintln(w, strings.Repeat("| --- ", len(fields))+"|")
+        for _, issue := range group.issues {
+            cells := []string{}
+            for _, value := range cardValues(issue, fields) {
+                cells = append(cells, markdownCell(value))
+            }
+            fmt.Fprintln(w, "| "+strings.Join(cells, " | ")+" |")
+        }
+    }
+}
+
+// writeBoard writes the grouped issues in the format
+func writeBoard(w io.Writer, format string, groups []boardGroup, fields []string) error {
+
+    switch format {
+    case "json":
+        encoder := json.NewEncoder(w)
+        encoder.SetIndent("", "  ")
+        return encoder.Encode(outputColumns(groups, fields))
+    case "yaml":
+        return yaml.NewEncoder(w).Encode(outputColumns(groups, fields))
+    case "csv", "tsv":
+        writer := csv.NewWriter(w)
+        if format == "tsv" {
+            writer.Comma = '\t'
+        }
+        writer.Write(append([]string{"column"}, fields...))
+        for _, group := range groups {
+            for _, issue := range group.issues {
+                writer.Write(append([]string{group.name}, cardValues(issue, fields)...))
+            }
+        }
+        writer.Flush()
+        return writer.Error()
+    case "markdown":
+        writeMarkdown(w, groups, fields)
+        return nil
+    }
+    return usageError("unknown output format \"" + format + "\", it can be " + strings.Join(outputFormats, ", "))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/jroimartin/gocui"
+)
+
+// picker is a popup list, the chosen line is handed over to onPick
+type picker struct {
+    options []string
+    onPick  func(g *gocui.Gui, choice string) error
+}
+
+var activePicker = &picker{}
+
+func openPicker(g *gocui.Gui, title string, options []string, onPick func(g *gocui.Gui, choice string) error) error {
+
+    activePicker = &picker{options: options, onPick: onPick}
+
+    maxX, maxY := g.Size()
+    height := len(options) + 1
+    if height > maxY-4 {
+        height = maxY - 4
+    }
+
+    if v, err := g.SetView("picker", maxX/2-25, maxY/2-height/2-1, maxX/2+25, maxY/2+height/2+1); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Editable = false
+        v.Highlight = true
+        v.Title = title
+        for _, option := range options {
+            fmt.Fprintln(v, option)
+        }
+    }
+
+    updateStatusBar(g, "Choose: "+keyHint("select")+"  |  Cancel: "+keyHint("close")+"  |  Help: "+keyHint("help"))
+    setCurrentViewOnTop(g, "picker")
+
+    return nil
+}
+
+func pickOption(g *gocui.Gui, v *gocui.View) error {
+    var line string
+    var err error
+
+    _, cy := v.Cursor()
+    if line, err = v.Line(cy); err != nil {
+        line = ""
+    }
+
+    destroyView(g, v)
+
+    if indexOf(line, activePicker.options) < 0 {
+        log.Debug("User picked nothing")
+        return nil
+    }
+    return activePicker.onPick(g, line)
+}
+
+var (
+    // activePrompt gets the line typed into the prompt
+    activePrompt func(g *gocui.Gui, text string) error
+    // promptCompleter completes the line on Tab, if the prompt has one
+    promptCompleter func(text string) (string, []string)
+)
+
+func openPrompt(g *gocui.Gui, title string, value string, onDone func(g *gocui.Gui, text string) error) error {
+
+    activePrompt = onDone
+    promptCompleter = nil
+
+    maxX, maxY := g.Size()
+    if v, err := g.SetView("prompt", maxX/2-30, maxY/2-1, maxX/2+30, maxY/2+1); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Editable = true
+        v.Title = title
+        fmt.Fprint(v, value)
+        v.SetCursor(len(value), 0)
+    }
+    g.Cursor = true
+    updateStatusBar(g, "Done: Enter  |  Cancel: Esc")
+    setCurrentViewOnTop(g, "prompt")
+
+    return nil
+}
+
+func submitPrompt(g *gocui.Gui, v *gocui.View) error {
+    text := strings.TrimSpace(v.Buffer())
+    destroyView(g, v)
+    return activePrompt(g, text)
+}
+
+func completePrompt(g *gocui.Gui, v *gocui.View) error {
+    if promptCompleter == nil {
+        return nil
+    }
+
+    completed, candidates := promptCompleter(strings.TrimSpace(v.Buffer()))
+    v.Clear()
+    fmt.Fprint(v, completed)
+
+    // Keep the end of a long line in sight
+    width, _ := v.Size()
+    if len(completed) < width {
+        v.SetOrigin(0, 0)
+        v.SetCursor(len(completed), 0)
+    } else {
+        v.SetOrigin(len(completed)-width+1, 0)
+        v.SetCursor(width-1, 0)
+    }
+
+    if len(candidates) > 0 {
+        updateStatusBar(g, strings.Join(candidates, "  "))
+    } else {
+        updateStatusBar(g, "Done: Enter  |  Complete: Tab  |  Cancel: Esc")
+    }
+    return nil
+}
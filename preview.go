@@ -0,0 +1,338 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+var (
+    // previewed is the issue in the preview, previewHistory the ones we
+    // jumped away from to get there.
+    previewed       = jira.Issue{}
+    previewHistory  = []jira.Issue{}
+    previewInfoText = "Jump to issue, open attachment or link: Enter  |  Add link: l  |  Remove link: d  |  Save attachment: s  |  Attach file: u  |  Back: Esc"
+)
+
+var issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[0-9]+\b`)
+
+// fetchIssue gets the issue from JIRA, or from the board if JIRA can't
+// be reached.
+func fetchIssue(issueKey string) (jira.Issue, error) {
+
+    err := ensureAuthenticated()
+    if err == nil {
+        issue, res, reqErr := jiraClient.Issue.Get(issueKey, nil)
+        if reqErr == nil {
+            return *issue, nil
+        }
+        err = newJiraError(res, reqErr)
+    }
+
+    if isOffline(err) {
+        for i := range kanbanMatrix {
+            if index := indexOfView(issueKey, kanbanMatrix[i].members); index >= 0 {
+                return kanbanMatrix[i].members[index].issue, nil
+            }
+        }
+    }
+    return jira.Issue{}, err
+}
+
+func showPreview(g *gocui.Gui, issue jira.Issue) error {
+
+    maxX, maxY := g.Size()
+    v, err := g.SetView("previewBox", 5, 3, maxX-5, maxY-3)
+    if err != nil && err != gocui.ErrUnknownView {
+        return err
+    }
+    v.Title = "Details of " + issue.Key
+    v.Autoscroll = false
+    v.Wrap = true
+    // Changes are made with "Edit issue", not here
+    v.Editable = false
+    v.Highlight = true
+    v.Clear()
+    v.SetOrigin(0, 0)
+    v.SetCursor(0, 0)
+
+    previewed = issue
+    previewDevLinks = map[string]string{}
+    printIssue(v, issue)
+    if _, ok := devCache[issue.Key]; !ok {
+        loadDevStatusInBackground(g, issue)
+    }
+
+    setCurrentViewOnTop(g, "previewBox")
+    updateStatusBar(g, previewInfoText)
+    return nil
+}
+
+// printIssue writes the details of the issue, for the preview and for
+// "jb show".
+func printIssue(v io.Writer, issue jira.Issue) {
+
+    lineSlice := strings.SplitN(
+        issue.Fields.Description,
+        "\r\n",
+        -1,
+    )
+    fmt.Fprintln(v, issue.Key+": "+issue.Fields.Summary)
+    if issue.Fields.Status != nil {
+        fmt.Fprintln(v, "Status: "+issue.Fields.Status.Name)
+    }
+    if issue.Fields.Reporter != nil {
+        fmt.Fprintln(v, "Reporter: "+issue.Fields.Reporter.DisplayName)
+    }
+    if issue.Fields.Assignee != nil {
+        fmt.Fprintln(v, "Assignee: "+issue.Fields.Assignee.DisplayName)
+    }
+    if len(issue.Fields.Labels) > 0 {
+        fmt.Fprintln(v, "Labels: "+strings.Join(issue.Fields.Labels, ","))
+    }
+    if issue.Fields.Priority != nil {
+        fmt.Fprintln(v, "Priority: "+colorPriority(issue.Fields.Priority.Name, issue.Fields.Priority.Name))
+    }
+    fmt.Fprintln(v, "Time: "+timeTracking(issue))
+    if t, ok := timers[issue.Key]; ok {
+        fmt.Fprintln(v, "Timer: running for "+formatDuration(time.Since(t.Started)))
+    }
+    if issue.Fields.Parent != nil {
+        fmt.Fprintln(v, "Parent: "+issue.Fields.Parent.Key)
+    }
+    printWatchers(v, issue)
+    printLinks(v, issue)
+    printAttachments(v, issue)
+    fmt.Fprint(v, "\nDescription:\n\n")
+    for i := range lineSlice {
+        fmt.Fprintln(v, lineSlice[i])
+    }
+    printComments(v, issue.Key)
+    // The preview loads it in background, it comes last for that
+    if status, ok := devCache[issue.Key]; ok {
+        printDevelopment(v, status)
+    }
+}
+
+// linkedIssueLine is how a linked issue or a subtask is listed
+func linkedIssueLine(issue *jira.Issue) string {
+    if issue.Fields == nil {
+        return issue.Key
+    }
+    status := ""
+    if issue.Fields.Status != nil {
+        status = " [" + issue.Fields.Status.Name + "]"
+    }
+    return issue.Key + status + " " + issue.Fields.Summary
+}
+
+// linkedIssue tells how the issue is linked to the other side, and what
+// the other side is.
+func linkedIssue(link *jira.IssueLink) (string, *jira.Issue) {
+    if link.OutwardIssue != nil {
+        return link.Type.Outward, link.OutwardIssue
+    }
+    return link.Type.Inward, link.InwardIssue
+}
+
+func printLinks(v io.Writer, issue jira.Issue) {
+
+    if len(issue.Fields.IssueLinks) > 0 {
+        fmt.Fprint(v, "\nLinks:\n")
+        for _, link := range issue.Fields.IssueLinks {
+            relation, other := linkedIssue(link)
+            if other == nil {
+                continue
+            }
+            fmt.Fprintln(v, "  "+relation+" "+linkedIssueLine(other))
+        }
+    }
+
+    if len(issue.Fields.Subtasks) > 0 {
+        fmt.Fprint(v, "\nSubtasks:\n")
+        for _, subtask := range issue.Fields.Subtasks {
+            fmt.Fprintln(v, "  "+linkedIssueLine(&jira.Issue{Key: subtask.Key, Fields: &subtask.Fields}))
+        }
+    }
+}
+
+// isBlocked tells if an issue which is not done yet blocks this one
+func isBlocked(issue jira.Issue) bool {
+    if issue.Fields == nil {
+        return false
+    }
+    for _, link := range issue.Fields.IssueLinks {
+        if link.InwardIssue == nil || !strings.Contains(strings.ToLower(link.Type.Inward), "blocked by") {
+            continue
+        }
+        fields := link.InwardIssue.Fields
+        if fields == nil || fields.Status == nil || fields.Status.StatusCategory.Key != "done" {
+            return true
+        }
+    }
+    return false
+}
+
+// keyUnderCursor finds the issue key on the highlighted line
+func keyUnderCursor(v *gocui.View) string {
+    _, cy := v.Cursor()
+    line, err := v.Line(cy)
+    if err != nil {
+        return ""
+    }
+    return issueKeyPattern.FindString(line)
+}
+
+func jumpToIssue(g *gocui.Gui, v *gocui.View) error {
+
+    if attachment := attachmentUnderCursor(v); attachment != nil {
+        return openAttachment(g, attachment)
+    }
+    if link := devLinkUnderCursor(v); link != "" {
+        return openDevLink(g, link)
+    }
+
+    issueKey := keyUnderCursor(v)
+    if issueKey == "" || issueKey == previewed.Key {
+        return nil
+    }
+
+    issue, err := fetchIssue(issueKey)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    previewHistory = append(previewHistory, previewed)
+    return showPreview(g, issue)
+}
+
+// closePreview goes back to the issue we jumped from, or closes it
+func closePreview(g *gocui.Gui, v *gocui.View) error {
+    if len(previewHistory) == 0 {
+        return destroyView(g, v)
+    }
+    last := previewHistory[len(previewHistory)-1]
+    previewHistory = previewHistory[:len(previewHistory)-1]
+    return showPreview(g, last)
+}
+
+// reloadPreview fetches the previewed issue again after a change
+func reloadPreview(g *gocui.Gui) error {
+    issue, err := fetchIssue(previewed.Key)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+    redrawCard(g, issue)
+    saveBoardCache()
+    return showPreview(g, issue)
+}
+
+// linkDirection is one side of a link type, as offered in the picker
+type linkDirection struct {
+    linkType jira.IssueLinkType
+    outward  bool
+}
+
+func addLinkHandler(g *gocui.Gui, v *gocui.View) error {
+
+    if err := ensureAuthenticated(); err != nil {
+        showError(g, err)
+        return nil
+    }
+    linkTypes, res, err := jiraClient.IssueLinkType.GetList()
+    if err != nil {
+        showError(g, newJiraError(res, err))
+        return nil
+    }
+
+    directions := map[string]linkDirection{}
+    options := []string{}
+    for _, t := range linkTypes {
+        for _, d := range []linkDirection{{t, true}, {t, false}} {
+            label := d.linkType.Inward
+            if d.outward {
+                label = d.linkType.Outward
+            }
+            // "relates to" is the same both ways
+            if _, ok := directions[label]; ok {
+                continue
+            }
+            directions[label] = d
+            options = append(options, label)
+        }
+    }
+    sort.Strings(options)
+
+    issueKey := previewed.Key
+    return openPicker(g, issueKey+" ...", options, func(g *gocui.Gui, choice string) error {
+        return openPrompt(g, issueKey+" "+choice+" (issue key)", "", func(g *gocui.Gui, otherKey string) error {
+            if otherKey == "" {
+                return nil
+            }
+            return addLink(g, issueKey, strings.ToUpper(otherKey), directions[choice])
+        })
+    })
+}
+
+func addLink(g *gocui.Gui, issueKey string, otherKey string, direction linkDirection) error {
+
+    // JIRA reads it as "inward issue <outward description> outward issue"
+    link := &jira.IssueLink{
+        Type:         jira.IssueLinkType{Name: direction.linkType.Name},
+        InwardIssue:  &jira.Issue{Key: issueKey},
+        OutwardIssue: &jira.Issue{Key: otherKey},
+    }
+    if !direction.outward {
+        link.InwardIssue, link.OutwardIssue = link.OutwardIssue, link.InwardIssue
+    }
+
+    if err := ensureAuthenticated(); err != nil {
+        showError(g, err)
+        return nil
+    }
+    if res, err := jiraClient.Issue.AddLink(link); err != nil {
+        showError(g, newJiraError(res, err))
+        return nil
+    }
+    return reloadPreview(g)
+}
+
+func removeLinkHandler(g *gocui.Gui, v *gocui.View) error {
+
+    issueKey := keyUnderCursor(v)
+    var found *jira.IssueLink
+    for _, link := range previewed.Fields.IssueLinks {
+        if _, other := linkedIssue(link); other != nil && other.Key == issueKey {
+            found = link
+            break
+        }
+    }
+    if found == nil {
+        updateStatusBar(g, "Choose a linked issue to remove its link")
+        return nil
+    }
+
+    relation, _ := linkedIssue(found)
+    question := "Remove: " + previewed.Key + " " + relation + " " + issueKey
+    return openPicker(g, "Remove the link?", []string{question, "Keep it"}, func(g *gocui.Gui, choice string) error {
+        if choice != question {
+            return nil
+        }
+        if err := ensureAuthenticated(); err != nil {
+            showError(g, err)
+            return nil
+        }
+        if res, err := jiraClient.Issue.DeleteLink(found.ID); err != nil {
+            showError(g, newJiraError(res, err))
+            return nil
+        }
+        return reloadPreview(g)
+    })
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "time"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// sprints of the board, when jb runs in scrum mode
+var sprints = []jira.Sprint{}
+
+// boardQuery is the query of the board, limited to the sprint we are
+// looking at in scrum mode.
+func boardQuery(conf configItem) string {
+    if conf.boardID == 0 || cache.Sprint.ID == 0 {
+        return conf.query
+    }
+    // ORDER BY can't go inside the parentheses
+    query := orderByPattern.ReplaceAllString(conf.query, "")
+    orderBy := orderByPattern.FindString(conf.query)
+    return "sprint = " + strconv.Itoa(cache.Sprint.ID) + " AND (" + query + ")" + orderBy
+}
+
+// loadSprints fetches the sprints of the board. We stay on the sprint we
+// are looking at, or go to the active one. Without an active or future
+// sprint the last closed one is shown, and the plain query if the board
+// has no sprints at all.
+func loadSprints(conf configItem) error {
+
+    if err := ensureAuthenticated(); err != nil {
+        return err
+    }
+
+    found := []jira.Sprint{}
+    options := &jira.GetAllSprintsOptions{State: "active,future,closed"}
+    for {
+        list, res, err := jiraClient.Board.GetAllSprintsWithOptions(conf.boardID, options)
+        if err != nil {
+            return newJiraError(res, err)
+        }
+        found = append(found, list.Values...)
+        if list.IsLast || len(list.Values) == 0 {
+            break
+        }
+        options.StartAt += len(list.Values)
+    }
+    sprints = found
+
+    for _, sprint := range sprints {
+        if sprint.ID == cache.Sprint.ID {
+            cache.Sprint = sprint
+            return nil
+        }
+    }
+    for _, state := range []string{"active", "future"} {
+        for _, sprint := range sprints {
+            if sprint.State == state {
+                cache.Sprint = sprint
+                return nil
+            }
+        }
+    }
+    last := jira.Sprint{}
+    for _, sprint := range sprints {
+        if sprint.State != "closed" {
+            continue
+        }
+        if last.ID == 0 || last.EndDate == nil || (sprint.EndDate != nil && !sprint.EndDate.Before(*last.EndDate)) {
+            last = sprint
+        }
+    }
+    cache.Sprint = last
+    return nil
+}
+
+// sprintMarker shows the sprint in the status bar
+func sprintMarker() string {
+    sprint := cache.Sprint
+    if sprint.ID == 0 {
+        return ""
+    }
+    marker := "[" + sprint.Name
+    if sprint.StartDate != nil && sprint.EndDate != nil {
+        marker = marker + " " + sprint.StartDate.Local().Format("02.01") + "-" + sprint.EndDate.Local().Format("02.01")
+    }
+    switch {
+    case sprint.State == "closed":
+        marker = marker + ", closed"
+    case sprint.State == "active" && sprint.EndDate != nil:
+        days := int(math.Ceil(time.Until(*sprint.EndDate).Hours() / 24))
+        marker = marker + fmt.Sprintf(", %d days left", days)
+    case sprint.State == "future":
+        marker = marker + ", not started"
+    }
+    return marker + "] "
+}
+
+// sprintLabel is how a sprint is listed in the pickers
+func sprintLabel(sprint jira.Sprint) string {
+    return sprint.Name + " (" + sprint.State + ")"
+}
+
+// sprintPicker lists the sprints in the given states, newest first
+func sprintPicker(g *gocui.Gui, title string, states []string, onPick func(g *gocui.Gui, sprint jira.Sprint) error) error {
+
+    options := []string{}
+    labels := map[string]jira.Sprint{}
+    for _, state := range states {
+        for i := len(sprints) - 1; i >= 0; i-- {
+            if sprints[i].State != state {
+                continue
+            }
+            label := sprintLabel(sprints[i])
+            labels[label] = sprints[i]
+            options = append(options, label)
+        }
+    }
+    if len(options) == 0 {
+        updateStatusBar(g, "No sprint to choose from")
+        return nil
+    }
+
+    return openPicker(g, title, options, func(g *gocui.Gui, choice string) error {
+        return onPick(g, labels[choice])
+    })
+}
+
+func switchSprintHandler(g *gocui.Gui, v *gocui.View) error {
+    if len(sprints) == 0 {
+        updateStatusBar(g, "Sprints are only there in scrum mode, set board_id")
+        return nil
+    }
+    return sprintPicker(g, "Show sprint", []string{"active", "future", "closed"}, func(g *gocui.Gui, sprint jira.Sprint) error {
+        cache.Sprint = sprint
+        return refreshBoard(g, nil)
+    })
+}
+
+func moveToSprintHandler(g *gocui.Gui, issue jira.Issue) error {
+    return sprintPicker(g, "Move "+issue.Key+" to sprint", []string{"active", "future"}, func(g *gocui.Gui, sprint jira.Sprint) error {
+        if err := ensureAuthenticated(); err != nil {
+            showError(g, err)
+            return nil
+        }
+        if res, err := jiraClient.Sprint.MoveIssuesToSprint(sprint.ID, []string{issue.ID}); err != nil {
+            showError(g, newJiraError(res, err))
+            return nil
+        }
+        if err := refreshBoard(g, nil); err != nil {
+            return err
+        }
+        updateStatusBar(g, issue.Key+" is moved to "+sprint.Name)
+        return nil
+    })
+}
+
+func moveToBacklog(g *gocui.Gui, issue jira.Issue) error {
+
+    if err := ensureAuthenticated(); err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    req, err := jiraClient.NewRequest("POST", "rest/agile/1.0/backlog/issue", map[string][]string{"issues": {issue.Key}})
+    if err != nil {
+        return err
+    }
+    if res, err := jiraClient.Do(req, nil); err != nil {
+        showError(g, newJiraError(res, err))
+        return nil
+    }
+
+    if err := refreshBoard(g, nil); err != nil {
+        return err
+    }
+    updateStatusBar(g, issue.Key+" is moved to the backlog")
+    return nil
+}
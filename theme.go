@@ -0,0 +1,307 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "hash/fnv"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/jroimartin/gocui"
+)
+
+// themeColor is a terminal color, -1 is the default of the terminal
+type themeColor int
+
+var defaultColor = themeColor(-1)
+
+type colorTheme struct {
+    frame               themeColor
+    background          themeColor
+    selection           themeColor
+    selectionBackground themeColor
+    status              themeColor
+    statusBackground    themeColor
+    // palette is where colorHash picks from
+    palette    []themeColor
+    components map[string]themeColor
+    priorities map[string]themeColor
+}
+
+var colorNames = map[string]themeColor{
+    "default": defaultColor,
+    "black":   0,
+    "red":     1,
+    "green":   2,
+    "yellow":  3,
+    "brown":   3,
+    "blue":    4,
+    "magenta": 5,
+    "purple":  5,
+    "cyan":    6,
+    "white":   7,
+    "gray":    7,
+}
+
+// themePresets are the themes to start from, dark is how jb always
+// looked.
+var themePresets = map[string]map[string]interface{}{
+    "dark": {
+        "frame":                "default",
+        "background":           "default",
+        "selection":            "blue",
+        "selection_background": "default",
+        "status":               "default",
+        "status_background":    "magenta",
+        "palette":              []interface{}{"red", "green", "yellow", "blue", "magenta", "cyan", "white"},
+    },
+    "light": {
+        "frame":                "black",
+        "background":           "default",
+        "selection":            "blue",
+        "selection_background": "default",
+        "status":               "black",
+        "status_background":    "cyan",
+        "palette":              []interface{}{"red", "green", "blue", "magenta", "cyan", "black"},
+    },
+    "high-contrast": {
+        "frame":                "white",
+        "background":           "black",
+        "selection":            "black",
+        "selection_background": "yellow",
+        "status":               "black",
+        "status_background":    "white",
+        "palette":              []interface{}{"red", "green", "yellow", "cyan", "magenta", "white"},
+    },
+}
+
+var theme = colorTheme{}
+
+// parseColor understands the names of the 8 basic colors, the numbers
+// of the 256 colors and "#rrggbb". termbox can't do more than 256 colors,
+// the nearest one of them is used for the latter.
+func parseColor(name string) (themeColor, error) {
+
+    name = strings.ToLower(strings.TrimSpace(name))
+    if color, ok := colorNames[name]; ok {
+        return color, nil
+    }
+    if number, err := strconv.Atoi(name); err == nil && number >= 0 && number <= 255 {
+        return themeColor(number), nil
+    }
+    if strings.HasPrefix(name, "#") && len(name) == 7 {
+        if rgb, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+            return rgbTo256(int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff)), nil
+        }
+    }
+    return defaultColor, errors.New("unknown color \"" + name + "\"")
+}
+
+// rgbTo256 finds the nearest color of the 6x6x6 cube of the 256 colors
+func rgbTo256(r int, g int, b int) themeColor {
+    level := func(value int) int {
+        if value < 48 {
+            return 0
+        }
+        if value < 115 {
+            return 1
+        }
+        return (value - 35) / 40
+    }
+    return themeColor(16 + 36*level(r) + 6*level(g) + level(b))
+}
+
+// attribute is the color for gocui, which counts from 1
+func (color themeColor) attribute() gocui.Attribute {
+    if color == defaultColor {
+        return gocui.ColorDefault
+    }
+    return gocui.Attribute(color + 1)
+}
+
+// ansi is the escape sequence to write in the color
+func (color themeColor) ansi() string {
+    switch {
+    case color == defaultColor:
+        return resetColor
+    case color < 8:
+        return fmt.Sprintf("\x1b[0;3%dm", color)
+    }
+    return fmt.Sprintf("\x1b[38;5;%dm", color)
+}
+
+// colorList reads a single color or a list of them
+func colorList(value interface{}) []string {
+    switch value := value.(type) {
+    case string:
+        return []string{value}
+    case []interface{}:
+        names := []string{}
+        for _, name := range value {
+            names = append(names, fmt.Sprint(name))
+        }
+        return names
+    case []string:
+        return value
+    }
+    return []string{fmt.Sprint(value)}
+}
+
+// loadTheme builds the theme from the preset and the theme section of
+// the configuration, and tells about every problem in it at once. The
+// terminal is put in 256 colors mode if the theme needs more than 8.
+func loadTheme(section map[string]interface{}) error {
+
+    problems := []string{}
+    presetName := "dark"
+    if name, ok := section["preset"]; ok {
+        presetName = fmt.Sprint(name)
+    }
+    preset, ok := themePresets[presetName]
+    if !ok {
+        problems = append(problems, "unknown theme preset \""+presetName+"\"")
+        preset = themePresets["dark"]
+    }
+
+    settings := map[string]interface{}{}
+    for name, value := range preset {
+        settings[name] = value
+    }
+    for name, value := range section {
+        if name != "preset" {
+            settings[name] = value
+        }
+    }
+
+    needs256 := false
+    color := func(setting string, name string) themeColor {
+        parsed, err := parseColor(name)
+        if err != nil {
+            problems = append(problems, setting+": "+err.Error())
+        }
+        needs256 = needs256 || parsed > 7
+        return parsed
+    }
+    colorMap := func(setting string) map[string]themeColor {
+        colors := map[string]themeColor{}
+        values, ok := settings[setting].(map[string]interface{})
+        if !ok {
+            if settings[setting] != nil {
+                problems = append(problems, setting+": should be names with their colors")
+            }
+            return colors
+        }
+        for name, value := range values {
+            colors[strings.ToLower(name)] = color(setting+"."+name, fmt.Sprint(value))
+        }
+        return colors
+    }
+
+    loaded := colorTheme{components: colorMap("components"), priorities: colorMap("priorities")}
+    for _, setting := range []struct {
+        name  string
+        color *themeColor
+    }{
+        {"frame", &loaded.frame},
+        {"background", &loaded.background},
+        {"selection", &loaded.selection},
+        {"selection_background", &loaded.selectionBackground},
+        {"status", &loaded.status},
+        {"status_background", &loaded.statusBackground},
+    } {
+        *setting.color = color(setting.name, fmt.Sprint(settings[setting.name]))
+    }
+    for _, name := range colorList(settings["palette"]) {
+        if parsed := color("palette", name); parsed != defaultColor {
+            loaded.palette = append(loaded.palette, parsed)
+        }
+    }
+    if len(loaded.palette) == 0 {
+        problems = append(problems, "palette: needs at least one color")
+    }
+
+    known := map[string]bool{"preset": true, "components": true, "priorities": true, "palette": true}
+    for name := range themePresets["dark"] {
+        known[name] = true
+    }
+    for name := range section {
+        if !known[name] {
+            problems = append(problems, "unknown theme setting \""+name+"\"")
+        }
+    }
+
+    if len(problems) > 0 {
+        sort.Strings(problems)
+        return &jbError{kind: errConfig, err: errors.New("Problems in the theme:\n  " + strings.Join(problems, "\n  "))}
+    }
+
+    theme = loaded
+    if needs256 {
+        outputMode = gocui.Output256
+    }
+    return nil
+}
+
+// applyTheme sets the colors of the frames and the selection
+func applyTheme(g *gocui.Gui) {
+    g.FgColor = theme.frame.attribute()
+    g.BgColor = theme.background.attribute()
+    g.SelFgColor = theme.selection.attribute()
+    g.SelBgColor = theme.selectionBackground.attribute()
+}
+
+// hashColor picks a color of the palette for the input, the same one
+// each time.
+func hashColor(input string) themeColor {
+    if len(theme.palette) == 0 {
+        return defaultColor
+    }
+    h := fnv.New32a()
+    h.Write([]byte(input))
+    return theme.palette[h.Sum32()%uint32(len(theme.palette))]
+}
+
+// componentThemeColor is the configured color of the component, or one
+// picked by its name.
+func componentThemeColor(name string) themeColor {
+    if color, ok := theme.components[strings.ToLower(name)]; ok {
+        return color
+    }
+    return hashColor(name)
+}
+
+func componentColor(name string) string {
+    return componentThemeColor(name).ansi()
+}
+
+// colorPriority writes the text in the color of the priority, if it has
+// one.
+func colorPriority(priority string, text string) string {
+    if color, ok := theme.priorities[strings.ToLower(priority)]; ok {
+        return color.ansi() + text + resetColor
+    }
+    return text
+}
+
+// basicColors are the 16 first colors as xterm shows them
+var basicColors = []string{
+    "#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+    "#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// hex is the color for the web, empty for the default
+func (color themeColor) hex() string {
+    switch {
+    case color == defaultColor:
+        return ""
+    case color < 16:
+        return basicColors[color]
+    case color < 232:
+        levels := []int{0, 95, 135, 175, 215, 255}
+        index := int(color) - 16
+        return fmt.Sprintf("#%02x%02x%02x", levels[index/36], levels[index/6%6], levels[index%6])
+    }
+    gray := 8 + 10*(int(color)-232)
+    return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+}
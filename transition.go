@@ -0,0 +1,128 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// getTransitions asks JIRA for the transitions of the issue. When JIRA
+// can't be reached, the ones we have seen before are used.
+func getTransitions(issueID string) ([]jira.Transition, error) {
+
+    err := ensureAuthenticated()
+    if err == nil {
+        transitions, res, reqErr := jiraClient.Issue.GetTransitions(issueID)
+        if reqErr == nil {
+            cacheTransitions(issueID, transitions)
+            return transitions, nil
+        }
+        err = newJiraError(res, reqErr)
+    }
+
+    if cached, ok := cache.Transitions[issueID]; ok && isOffline(err) {
+        return cached, nil
+    }
+    return nil, err
+}
+
+// getTransitionFields fetches the fields on the screen of a transition.
+// go-jira only tells us if they are required, so we ask for ourselves.
+func getTransitionFields(issueID string, transitionID string) (map[string]fieldMeta, error) {
+
+    apiEndpoint := fmt.Sprintf(
+        "rest/api/2/issue/%s/transitions?expand=transitions.fields&transitionId=%s",
+        issueID,
+        transitionID,
+    )
+    req, err := jiraClient.NewRequest("GET", apiEndpoint, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    result := struct {
+        Transitions []struct {
+            ID     string               `json:"id"`
+            Fields map[string]fieldMeta `json:"fields"`
+        } `json:"transitions"`
+    }{}
+    if res, err := jiraClient.Do(req, &result); err != nil {
+        return nil, newJiraError(res, err)
+    }
+
+    for _, t := range result.Transitions {
+        if t.ID == transitionID {
+            return t.Fields, nil
+        }
+    }
+    return nil, errors.New("Transition is not available anymore")
+}
+
+// transitionPayload builds the body of the transition call. Comment and
+// time spent are not fields, JIRA wants them as updates.
+func transitionPayload(transitionID string, fields []formField) (map[string]interface{}, error) {
+
+    payloadFields := map[string]interface{}{}
+    payloadUpdate := map[string]interface{}{}
+
+    for _, f := range fields {
+        if f.value == "" {
+            continue
+        }
+        switch {
+        case f.meta.Schema.System == "comment":
+            payloadUpdate["comment"] = []map[string]interface{}{
+                {"add": map[string]string{"body": f.value}},
+            }
+        case f.meta.Schema.System == "worklog":
+            payloadUpdate["worklog"] = []map[string]interface{}{
+                {"add": map[string]string{"timeSpent": f.value}},
+            }
+        case f.meta.Schema.System == "timetracking":
+            payloadFields[f.id] = map[string]string{"remainingEstimate": f.value}
+        default:
+            value, err := fieldValue(f)
+            if err != nil {
+                return nil, err
+            }
+            payloadFields[f.id] = value
+        }
+    }
+
+    return map[string]interface{}{
+        "transition": map[string]string{"id": transitionID},
+        "fields":     payloadFields,
+        "update":     payloadUpdate,
+    }, nil
+}
+
+// openTransitionForm shows the screen of the transition and hands the
+// filled payload over to submit. Errors of submit are shown on the form.
+func openTransitionForm(
+    g *gocui.Gui,
+    issueID string,
+    issueKey string,
+    transition jira.Transition,
+    submit func(g *gocui.Gui, payload interface{}) error,
+) error {
+
+    metas, err := getTransitionFields(issueID, transition.ID)
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    return openForm(g, &fieldForm{
+        title:  transition.Name + " " + issueKey,
+        fields: formFieldsFromMeta(metas),
+        submit: func(g *gocui.Gui, form *fieldForm) error {
+            payload, err := transitionPayload(transition.ID, form.fields)
+            if err != nil {
+                return err
+            }
+            return submit(g, payload)
+        },
+    })
+}
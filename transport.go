@@ -0,0 +1,224 @@
+package main
+
+import (
+    "io"
+    "io/ioutil"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+var (
+    retryAttempts = 4
+    retryBaseWait = 500 * time.Millisecond
+    retryMaxWait  = 30 * time.Second
+)
+
+// statusNotifier is how the transport talks to the user. It is replaced
+// by a status bar writer as soon as the UI is up.
+var statusNotifier = func(msg string) {
+    log.Info(msg)
+}
+
+// retryTransport sits between go-jira and the network. It logs in again
+// when JIRA forgets our session, and retries requests which failed for
+// reasons that may go away by themselves.
+type retryTransport struct {
+    base    http.RoundTripper
+    reauth  func() error
+    authMu  sync.Mutex
+    cookies []*http.Cookie
+}
+
+// isSessionRequest tells if this is the login call itself, which should
+// go through untouched.
+func isSessionRequest(req *http.Request) bool {
+    return strings.HasSuffix(req.URL.Path, "rest/auth/1/session")
+}
+
+func isIdempotent(req *http.Request) bool {
+    switch req.Method {
+    case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+        return true
+    }
+    return false
+}
+
+// sessionExpired checks if JIRA refused us because the session cookie
+// is not valid anymore.
+func sessionExpired(res *http.Response) bool {
+    if res.StatusCode == http.StatusUnauthorized {
+        return true
+    }
+    return res.StatusCode == http.StatusForbidden &&
+        strings.Contains(res.Header.Get("X-Seraph-LoginReason"), "AUTHENTICATED_FAILED")
+}
+
+// retryWait decides whether the request should be sent again and how
+// long to wait before that.
+func retryWait(req *http.Request, res *http.Response, err error, attempt int) (time.Duration, bool) {
+
+    if attempt >= retryAttempts || req.Context().Err() != nil {
+        return 0, false
+    }
+    if req.Body != nil && req.GetBody == nil {
+        return 0, false
+    }
+
+    wait := retryBaseWait << uint(attempt-1)
+    if wait > retryMaxWait {
+        wait = retryMaxWait
+    }
+
+    if err != nil {
+        return wait, isIdempotent(req)
+    }
+
+    switch res.StatusCode {
+    case http.StatusTooManyRequests:
+        // JIRA didn't process it, so it is safe to send anything again
+    case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+        if !isIdempotent(req) {
+            return 0, false
+        }
+    default:
+        return 0, false
+    }
+
+    if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+        wait = retryAfter
+        if wait > retryMaxWait {
+            wait = retryMaxWait
+        }
+    }
+    return wait, true
+}
+
+// parseRetryAfter understands both forms of the header, seconds or date
+func parseRetryAfter(value string) (time.Duration, bool) {
+    if value == "" {
+        return 0, false
+    }
+    if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+        return time.Duration(seconds) * time.Second, true
+    }
+    if date, err := http.ParseTime(value); err == nil {
+        wait := time.Until(date)
+        if wait < 0 {
+            wait = 0
+        }
+        return wait, true
+    }
+    return 0, false
+}
+
+// prepare copies the request for another attempt, with a fresh body and
+// with the new session cookie if we had to log in again.
+func (t *retryTransport) prepare(req *http.Request, resend bool, reauthenticated bool) (*http.Request, error) {
+
+    clone := req.Clone(req.Context())
+    if resend && req.GetBody != nil {
+        body, err := req.GetBody()
+        if err != nil {
+            return nil, err
+        }
+        clone.Body = body
+    }
+
+    if reauthenticated {
+        t.authMu.Lock()
+        clone.Header.Del("Cookie")
+        for _, cookie := range t.cookies {
+            clone.AddCookie(cookie)
+        }
+        t.authMu.Unlock()
+    }
+
+    return clone, nil
+}
+
+// refreshSession logs in again. The login call passes through this
+// transport too, that's where the new cookies are picked up.
+func (t *retryTransport) refreshSession() error {
+    statusNotifier("Session expired, logging in again...")
+    return t.reauth()
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+    if isSessionRequest(req) {
+        res, err := t.base.RoundTrip(req)
+        if err == nil && res.StatusCode == http.StatusOK {
+            t.authMu.Lock()
+            t.cookies = res.Cookies()
+            t.authMu.Unlock()
+        }
+        return res, err
+    }
+
+    canResend := req.Body == nil || req.GetBody != nil
+    reauthenticated := false
+    resend := false
+    for attempt := 1; ; attempt++ {
+        attemptReq, err := t.prepare(req, resend, reauthenticated)
+        if err != nil {
+            return nil, err
+        }
+
+        res, err := t.base.RoundTrip(attemptReq)
+        resend = true
+
+        if err == nil && sessionExpired(res) && !reauthenticated && canResend && t.reauth != nil {
+            if reauthErr := t.refreshSession(); reauthErr != nil {
+                log.Warn("Logging in again failed: " + reauthErr.Error())
+                return res, nil
+            }
+            drainBody(res)
+            reauthenticated = true
+            attempt = 0
+            continue
+        }
+
+        wait, retry := retryWait(req, res, err, attempt)
+        if !retry {
+            if attempt > 1 && (err != nil || res.StatusCode >= 400) {
+                statusNotifier("JIRA request failed after " + strconv.Itoa(attempt) + " attempts: " + failureReason(res, err))
+            }
+            return res, err
+        }
+
+        log.Warn("Retrying " + req.Method + " " + req.URL.Path + ": " + failureReason(res, err))
+        if res != nil {
+            drainBody(res)
+        }
+        statusNotifier("JIRA is not answering (" + failureReason(res, err) + "), retrying in " + wait.String() + "...")
+
+        select {
+        case <-time.After(wait):
+        case <-req.Context().Done():
+            return nil, req.Context().Err()
+        }
+    }
+}
+
+func failureReason(res *http.Response, err error) string {
+    if err != nil {
+        return err.Error()
+    }
+    return res.Status
+}
+
+// drainBody reads the rest of the body, so the connection can be reused
+func drainBody(res *http.Response) {
+    io.Copy(ioutil.Discard, res.Body)
+    res.Body.Close()
+}
+
+// newJiraHTTPClient builds the HTTP client go-jira will use. The login
+// function is set later, since it needs the JIRA client itself.
+func newJiraHTTPClient() (*http.Client, *retryTransport) {
+    transport := &retryTransport{base: http.DefaultTransport}
+    return &http.Client{Transport: transport}, transport
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// flagFieldName is the custom field JIRA Software uses for flags
+var flagFieldName = "Flagged"
+
+var flaggedFirst = false
+
+func isWatching(issue jira.Issue) bool {
+    return issue.Fields != nil && issue.Fields.Watches != nil && issue.Fields.Watches.IsWatching
+}
+
+// votes tells how many votes the issue has, and if one of them is ours.
+// go-jira doesn't know the field, it is in the unknowns.
+func votes(issue jira.Issue) (int, bool) {
+    if issue.Fields == nil {
+        return 0, false
+    }
+    field, ok := issue.Fields.Unknowns["votes"].(map[string]interface{})
+    if !ok {
+        return 0, false
+    }
+    count, _ := field["votes"].(float64)
+    hasVoted, _ := field["hasVoted"].(bool)
+    return int(count), hasVoted
+}
+
+func isFlagged(issue jira.Issue) bool {
+    if issue.Fields == nil || cache.FlagField == "" {
+        return false
+    }
+    values, ok := issue.Fields.Unknowns[cache.FlagField].([]interface{})
+    return ok && len(values) > 0
+}
+
+// sortFlaggedFirst puts the flagged issues in front, keeping the order
+// of the query otherwise.
+func sortFlaggedFirst(issues []jira.Issue) []jira.Issue {
+    sorted := append([]jira.Issue{}, issues...)
+    sort.SliceStable(sorted, func(i, j int) bool {
+        return isFlagged(sorted[i]) && !isFlagged(sorted[j])
+    })
+    return sorted
+}
+
+// triageMenuItems are the watch, vote and flag lines of the menu, as
+// they fit the issue.
+func triageMenuItems(issue jira.Issue) []string {
+    items := []string{"Watch issue", "Vote for issue"}
+    if isWatching(issue) {
+        items[0] = "Stop watching"
+    }
+    if _, hasVoted := votes(issue); hasVoted {
+        items[1] = "Remove vote"
+    }
+    if cache.FlagField != "" {
+        if isFlagged(issue) {
+            items = append(items, "Remove flag")
+        } else {
+            items = append(items, "Flag issue")
+        }
+    }
+    return items
+}
+
+// runTriageAction runs one of triageMenuItems on the issue
+func runTriageAction(g *gocui.Gui, issue jira.Issue, action string) error {
+
+    if err := ensureAuthenticated(); err != nil {
+        showError(g, err)
+        return nil
+    }
+    conf, err := readConfig()
+    if err != nil {
+        showError(g, err)
+        return nil
+    }
+
+    var res *jira.Response
+    switch action {
+    case "Watch issue":
+        res, err = jiraClient.Issue.AddWatcher(issue.ID, conf.username)
+    case "Stop watching":
+        res, err = jiraClient.Issue.RemoveWatcher(issue.ID, conf.username)
+    case "Vote for issue", "Remove vote":
+        method := "POST"
+        if action == "Remove vote" {
+            method = "DELETE"
+        }
+        var req *http.Request
+        if req, err = jiraClient.NewRequest(method, "rest/api/2/issue/"+issue.Key+"/votes", nil); err == nil {
+            res, err = jiraClient.Do(req, nil)
+        }
+    case "Flag issue", "Remove flag":
+        var value interface{}
+        if action == "Flag issue" {
+            value = []map[string]string{{"value": "Impediment"}}
+        }
+        res, err = jiraClient.Issue.UpdateIssue(issue.Key, map[string]interface{}{
+            "fields": map[string]interface{}{cache.FlagField: value},
+        })
+    }
+    if err != nil {
+        showError(g, newJiraError(res, err))
+        return nil
+    }
+
+    // The flag moves the card in its column
+    if flaggedFirst && (action == "Flag issue" || action == "Remove flag") {
+        if err := refreshBoard(g, nil); err != nil {
+            return err
+        }
+    } else {
+        refreshCard(g, issue.Key)
+    }
+    updateStatusBar(g, action+": "+issue.Key)
+    return nil
+}
+
+// printWatchers writes the watchers and the votes to the preview
+func printWatchers(v io.Writer, issue jira.Issue) {
+
+    count, _ := votes(issue)
+    fmt.Fprintln(v, "Votes: "+strconv.Itoa(count))
+
+    if err := ensureAuthenticated(); err != nil {
+        return
+    }
+    // GetWatchers of go-jira needs accountId, which JIRA Server doesn't
+    // send, and panics without it.
+    req, err := jiraClient.NewRequest("GET", "rest/api/2/issue/"+issue.Key+"/watchers", nil)
+    if err != nil {
+        return
+    }
+    watches := jira.Watches{}
+    if res, err := jiraClient.Do(req, &watches); err != nil {
+        log.Warn("Couldn't fetch the watchers of " + issue.Key + ": " + newJiraError(res, err).Error())
+        return
+    }
+    names := []string{}
+    for _, watcher := range watches.Watchers {
+        names = append(names, watcher.DisplayName)
+    }
+    if len(names) > 0 {
+        fmt.Fprintln(v, "Watchers: "+strings.Join(names, ", "))
+    }
+}
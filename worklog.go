@@ -0,0 +1,183 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strconv"
+    "time"
+
+    jira "github.com/andygrunwald/go-jira"
+    "github.com/jroimartin/gocui"
+)
+
+// workTimer is a running timer on a card. They live in ~/.jb, so they
+// survive a restart of jb.
+type workTimer struct {
+    IssueID  string    `json:"issueId"`
+    IssueKey string    `json:"issueKey"`
+    Started  time.Time `json:"started"`
+}
+
+var timers = map[string]workTimer{}
+
+// durationPattern is what JIRA accepts as time spent, e.g. "1h 30m"
+var durationPattern = regexp.MustCompile(`^\s*(\d+w\s*)?(\d+d\s*)?(\d+h\s*)?(\d+m\s*)?$`)
+
+// startedLayout is how the start time of a worklog is typed
+var startedLayout = "2006-01-02 15:04"
+
+func loadTimers() {
+    if err := readJSONFile(jbDir(), "timers.json", &timers); err != nil {
+        timers = map[string]workTimer{}
+    }
+}
+
+func saveTimers() {
+    writeJSONFile(jbDir(), "timers.json", timers)
+}
+
+// formatDuration writes the duration the way JIRA does, in hours and
+// minutes. Anything under a minute counts as one.
+func formatDuration(d time.Duration) string {
+    minutes := int(d.Round(time.Minute).Minutes())
+    if minutes < 1 {
+        minutes = 1
+    }
+    switch {
+    case minutes < 60:
+        return strconv.Itoa(minutes) + "m"
+    case minutes%60 == 0:
+        return strconv.Itoa(minutes/60) + "h"
+    }
+    return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+// timeTracking is the time tracking line of the preview
+func timeTracking(issue jira.Issue) string {
+    seconds := func(s int) string {
+        if s == 0 {
+            return "-"
+        }
+        return formatDuration(time.Duration(s) * time.Second)
+    }
+    return fmt.Sprintf(
+        "Original: %s  |  Remaining: %s  |  Logged: %s",
+        seconds(issue.Fields.TimeOriginalEstimate),
+        seconds(issue.Fields.TimeEstimate),
+        seconds(issue.Fields.TimeSpent),
+    )
+}
+
+// timerMarker is added to the card of an issue with a running timer
+func timerMarker(issueKey string) string {
+    if t, ok := timers[issueKey]; ok {
+        return "\n[timer since " + t.Started.Format("15:04") + "]"
+    }
+    return ""
+}
+
+func toggleTimerHandler(g *gocui.Gui, v *gocui.View) error {
+    currentColumn, err := getColumn(active.columnname)
+    if err != nil || len(currentColumn.members) == 0 {
+        return nil
+    }
+    return toggleTimer(g, currentColumn.members[active.indexno].issue)
+}
+
+// toggleTimer starts the timer of the issue, or stops it and offers the
+// elapsed time as a worklog. The timer only goes away once it is logged.
+func toggleTimer(g *gocui.Gui, issue jira.Issue) error {
+
+    t, running := timers[issue.Key]
+    if !running {
+        timers[issue.Key] = workTimer{IssueID: issue.ID, IssueKey: issue.Key, Started: time.Now()}
+        saveTimers()
+        redrawCard(g, issue)
+        updateStatusBar(g, "Timer started on "+issue.Key)
+        return nil
+    }
+
+    return openWorklogForm(g, issue, formatDuration(time.Since(t.Started)), t.Started, func() {
+        delete(timers, issue.Key)
+        saveTimers()
+    })
+}
+
+// openWorklogForm asks for the time spent, when the work started and a
+// comment. onLogged runs after JIRA accepted the worklog.
+func openWorklogForm(g *gocui.Gui, issue jira.Issue, spent string, started time.Time, onLogged func()) error {
+
+    fields := []formField{
+        {
+            id:    "timeSpent",
+            meta:  fieldMeta{Required: true, Name: "Time spent", Schema: fieldSchema{Type: "string", System: "worklog"}},
+            value: spent,
+        },
+        {
+            id:    "started",
+            meta:  fieldMeta{Required: true, Name: "Started", Schema: fieldSchema{Type: "datetime"}},
+            value: started.Format(startedLayout),
+        },
+        {
+            id:   "comment",
+            meta: fieldMeta{Name: "Comment", Schema: fieldSchema{Type: "string", System: "comment"}},
+        },
+    }
+
+    return openForm(g, &fieldForm{
+        title:  "Log work on " + issue.Key,
+        fields: fields,
+        submit: func(g *gocui.Gui, form *fieldForm) error {
+            spent := form.fields[0].value
+            if !durationPattern.MatchString(spent) {
+                return &jbError{kind: errValidation, err: errors.New("Time spent should look like 1h 30m, not " + spent)}
+            }
+            started, err := time.ParseInLocation(startedLayout, form.fields[1].value, time.Local)
+            if err != nil {
+                return &jbError{kind: errValidation, err: errors.New("Started should look like " + startedLayout)}
+            }
+            startedAt := jira.Time(started)
+            record := &jira.WorklogRecord{
+                TimeSpent: spent,
+                Started:   &startedAt,
+                Comment:   form.fields[2].value,
+            }
+            if err := logWork(issue.Key, record); err != nil {
+                return err
+            }
+            if onLogged != nil {
+                onLogged()
+            }
+            refreshCard(g, issue.Key)
+            updateStatusBar(g, "Logged "+spent+" on "+issue.Key)
+            return nil
+        },
+    })
+}
+
+func logWorkHandler(g *gocui.Gui, issue jira.Issue) error {
+    return openWorklogForm(g, issue, "", time.Now(), nil)
+}
+
+func logWork(issueKey string, record *jira.WorklogRecord) error {
+    if err := ensureAuthenticated(); err != nil {
+        return err
+    }
+    if _, res, err := jiraClient.Issue.AddWorklogRecord(issueKey, record); err != nil {
+        return newJiraError(res, err)
+    }
+    return nil
+}
+
+// refreshCard fetches the issue again, so the card and the preview show
+// what JIRA has now.
+func refreshCard(g *gocui.Gui, issueKey string) {
+    issue, err := fetchIssue(issueKey)
+    if err != nil {
+        showError(g, err)
+        return
+    }
+    redrawCard(g, issue)
+    saveBoardCache()
+}